@@ -3,15 +3,18 @@ package codebook
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/rs/zerolog"
-	"google.golang.org/protobuf/proto"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 
 	"github.com/moderntv/codebook-cache/internal/aggregator"
-	"github.com/moderntv/codebook-cache/internal/invalidation"
 	"github.com/moderntv/codebook-cache/internal/memsize"
 	metrics_pkg "github.com/moderntv/codebook-cache/internal/metrics"
 	"github.com/moderntv/codebook-cache/internal/utils"
@@ -19,214 +22,673 @@ import (
 
 type Cache[K comparable, T any] struct {
 	// static attributes (does not change its value after initialization)
-	ctx            context.Context
-	log            zerolog.Logger
-	metrics        *metrics_pkg.Metrics
-	name           string
-	timeouts       Timeouts
-	loadAllFunc    LoadAllFunc[K, T]
-	reloadChan     chan bool
-	aggregator     *aggregator.SimpleAggregator
-	memSizeEnabled bool
+	ctx                   context.Context
+	cancel                context.CancelFunc
+	log                   zerolog.Logger
+	metrics               *metrics_pkg.Metrics
+	name                  string
+	timeouts              Timeouts
+	loadAllFunc           LoadAllVersionedFunc[K, T]
+	versioned             bool
+	loadChangesFunc       LoadChangesFunc[K, T]
+	loadByKeysFunc        LoadByKeysFunc[K, T]
+	indexes               []Index[K, T]
+	equalFunc             EqualFunc[T]
+	cloneFunc             CloneFunc[T] // nil when items are not cloned on read
+	validators            []ValidateFunc[K, T]
+	restartOnInvalidation bool
+	tracer                trace.Tracer
+	propagator            propagation.TextMapPropagator
+	reloadChan            chan bool
+	aggregator            *aggregator.SimpleAggregator
+	memSizeEnabled        bool
+	snapshotStore         SnapshotStore[K, T]
+	invalidationSources   []InvalidationSource[K]
+	closeFuncs            []func() // called when cache is closed
 	// dynamic attributes (not using mutex)
 	memSizeValue atomic.Uint64
 	data         atomic.Value
+	stale        atomic.Bool    // data were restored from snapshot store and not loaded yet
+	persistMu    sync.Mutex     // serializes writes into snapshot store
+	persistWg    sync.WaitGroup // running writes into snapshot store
 	// attributes protected by mutex
 	mu          sync.Mutex
 	isReloading bool
+	reloadDone  chan struct{}           // closed when running reload finishes
+	cancelLoad  context.CancelCauseFunc // cancels running load
+	closed      bool
 	nextReload  *time.Time
+	// last successful full reload (used only when loading changes is enabled)
+	lastFullReload time.Time
+	// invalidations waiting for reload
+	pendingFull   bool
+	pendingKeys   map[K]struct{}
+	pendingReason reloadReason // reason of reload performed by aggregator
+	// spans propagated in invalidation messages waiting for reload
+	pendingSpans []trace.SpanContext
+	// reason of forced reload requested while reloading, another reload will follow
+	// (empty when not requested)
+	reloadRequested reloadReason
+	// results of finished reloads
+	consecutiveFailures int
+	lastAttempt         time.Time // start of the last finished reload
+	lastSuccess         time.Time // start of the last successful reload
+	lastErr             error     // error of the last finished reload
+	// sequence number of the last started reload
+	reloadSeq uint64
+	waiters   []reloadWaiter
+	// attributes protected by subscribers mutex
+	subscribersMu    sync.Mutex
+	subscribers      map[uint64]ChangeHandler[K]
+	reloadListeners  map[uint64]func()
+	nextSubscriberID uint64
 }
 
 func New[K comparable, T any](params Params[K, T]) (c *Cache[K, T], err error) {
+	c, err = newCache(params)
+	if err != nil {
+		return
+	}
+
+	err = c.start(c.ctx)
+	if err != nil {
+		c = nil
+		return
+	}
+
+	return
+}
+
+// newCache creates cache without loading any data.
+func newCache[K comparable, T any](params Params[K, T]) (c *Cache[K, T], err error) {
 	err = params.check()
 	if err != nil {
 		return
 	}
 
 	var metrics *metrics_pkg.Metrics
-	if params.MetricsRegistry != nil {
+	switch {
+	case params.MetricsRegistry != nil:
 		metrics, err = metrics_pkg.New(params.Name, params.MetricsRegistry)
-		if err != nil {
-			return
-		}
+	case params.MetricsRegisterer != nil:
+		metrics, err = metrics_pkg.NewWithRegisterer(params.Name, params.MetricsRegisterer)
+	}
+	if err != nil {
+		return
 	}
 
 	log := params.Log.With().Str("cache", params.Name).Logger()
 
+	equalFunc := params.EqualFunc
+	if equalFunc == nil {
+		equalFunc = DefaultEqual[T]
+	}
+
+	var cloneFunc CloneFunc[T]
+	if params.CloneOnRead {
+		cloneFunc = params.CloneFunc
+		if cloneFunc == nil {
+			cloneFunc = DefaultClone[T]
+		}
+	}
+
+	tracerProvider := params.TracerProvider
+	if tracerProvider == nil {
+		tracerProvider = noop.NewTracerProvider()
+	}
+	propagator := params.Propagator
+	if propagator == nil {
+		propagator = propagation.TraceContext{}
+	}
+
+	timeouts := params.Timeouts
+	if params.LoadChangesFunc != nil && timeouts.FullReloadInterval == 0 {
+		timeouts.FullReloadInterval = defaultFullReloadInterval
+	}
+
+	ctx, cancel := context.WithCancel(params.Context)
+
 	c = &Cache[K, T]{
-		ctx:            params.Context,
-		log:            log,
-		metrics:        metrics,
-		name:           params.Name,
-		timeouts:       params.Timeouts,
-		loadAllFunc:    params.LoadAllFunc,
-		reloadChan:     make(chan bool, 1),
-		memSizeEnabled: params.MemsizeEnabled,
+		ctx:                   ctx,
+		cancel:                cancel,
+		log:                   log,
+		metrics:               metrics,
+		name:                  params.Name,
+		timeouts:              timeouts,
+		loadAllFunc:           params.loadAllVersionedFunc(),
+		versioned:             params.LoadAllVersionedFunc != nil,
+		loadChangesFunc:       params.LoadChangesFunc,
+		loadByKeysFunc:        params.LoadByKeysFunc,
+		indexes:               params.Indexes,
+		equalFunc:             equalFunc,
+		cloneFunc:             cloneFunc,
+		validators:            params.Validators,
+		restartOnInvalidation: params.RestartOnInvalidation,
+		tracer:                tracerProvider.Tracer(tracerName),
+		propagator:            propagator,
+		reloadChan:            make(chan bool, 1),
+		memSizeEnabled:        params.MemsizeEnabled,
+		snapshotStore:         params.SnapshotStore,
+		invalidationSources:   params.invalidationSources(log),
+	}
+
+	if metrics != nil {
+		metrics.ReloadInterval.Set(params.Timeouts.ReloadInterval.Seconds())
 	}
 
 	if params.Timeouts.ReloadDelay > 0 {
 		c.aggregator = aggregator.NewSimpleAggregator(
-			params.Context,
+			ctx,
 			log,
 			params.Timeouts.ReloadDelay,
 			func() {
-				_ = c.reload(true)
+				_ = c.reload(c.takePendingReason())
 			},
 		)
 	} else {
 		c.log.Warn().Msg("invalidations aggregation is disabled")
 	}
 
-	err = c.reload(true)
+	return
+}
+
+// start performs initial load (cancelled also when `ctx` is done) and starts periodic reloads
+// and invalidations. When initial load fails, all resources of cache are released.
+func (c *Cache[K, T]) start(ctx context.Context) (err error) {
+	loadCtx, cancelLoad := context.WithCancelCause(c.ctx)
+	stop := context.AfterFunc(ctx, func() {
+		cancelLoad(context.Cause(ctx))
+	})
+	err = c.reloadContext(loadCtx, reasonInitial)
+	stop()
+	// load functions usually return only `ctx.Err()`, cause (e.g. start timeout) is added to it
+	if cause := context.Cause(loadCtx); err != nil && cause != nil && !errors.Is(err, cause) {
+		err = fmt.Errorf("initial load cancelled: %w: %w", cause, err)
+	}
+	cancelLoad(nil)
+
+	if err != nil && c.snapshotStore != nil {
+		err = c.restoreSnapshot(err)
+	}
+	if err == nil {
+		// invalidation messages
+		err = c.initInvalidations()
+	}
 	if err != nil {
+		c.cancel()
+		if c.metrics != nil {
+			c.metrics.Unregister()
+		}
 		return
 	}
 
 	// set next reload and time checker
 	c.initPeriodicReload()
 
-	// invalidation messages
-	if params.Invalidations != nil {
-		c.initInvalidations(params.Invalidations)
-	} else {
-		c.log.Warn().Msg("invalidations are disabled")
-	}
-
 	return
 }
 
 func (c *Cache[K, T]) Get(ID K) *T {
-	entries := c.GetAll()
+	entries := c.loadData().entries
 	// no additional locking is needed here, because the cache is never modified (just replaced)
 	entry, exists := entries[ID]
 	if !exists {
 		return nil
 	}
 
-	return entry
+	return c.cloneItem(entry)
 }
 
+// GetAll returns map of all items. The map is shared by all callers and must not be modified
+// (use `Snapshot` for read-only access). With `Params.CloneOnRead`, a copy with cloned items
+// is returned instead.
 func (c *Cache[K, T]) GetAll() (entries map[K]*T) {
-	entries = c.data.Load().(map[K]*T) // cache is always set
+	if c.cloneFunc != nil {
+		return c.Snapshot().ToMap()
+	}
+
+	entries = c.loadData().entries
 	return
 }
 
+// cloneItem returns clone of item when cloning on read is enabled
+func (c *Cache[K, T]) cloneItem(item *T) *T {
+	if c.cloneFunc == nil {
+		return item
+	}
+
+	return c.cloneFunc(item)
+}
+
+func (c *Cache[K, T]) loadData() *dataset[K, T] {
+	return c.data.Load().(*dataset[K, T]) // cache is always set
+}
+
+// IsStale returns true when cache serves data restored from `Params.SnapshotStore`
+// because loading failed during cache creation. Cache stops being stale after
+// the first successful load.
+func (c *Cache[K, T]) IsStale() bool {
+	return c.stale.Load()
+}
+
 func (c *Cache[K, T]) InvalidateAll() {
+	c.invalidateAll(reasonManual)
+}
+
+func (c *Cache[K, T]) invalidateAll(reason reloadReason) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.pendingFull = true
+	c.mu.Unlock()
+
+	c.invalidate(reason)
+}
+
+// invalidate triggers reload of invalidated items
+func (c *Cache[K, T]) invalidate(reason reloadReason) {
 	if c.aggregator != nil {
+		c.mu.Lock()
+		c.pendingReason = mergeReasons(c.pendingReason, reason)
+		c.mu.Unlock()
+
 		c.aggregator.Notify()
 		return
 	}
 
-	go c.reload(true)
+	go c.reload(reason)
 }
 
-func (c *Cache[K, T]) initInvalidations(invalidations *Invalidations) {
-	natsHelper := invalidation.NewNatsHelper(c.log, invalidations.Nats, invalidations.Prefix)
+// takePendingReason returns reason of invalidations aggregated for the next reload
+func (c *Cache[K, T]) takePendingReason() (reason reloadReason) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	for subject, message := range invalidations.Messages {
-		// subscribe to invalidation message
-		natsHelper.Subscribe(subject, message, func(_ proto.Message) {
-			// invalidate whole repository
-			c.log.Trace().Msg("Invalidate")
-			if c.metrics != nil {
+	reason = mergeReasons(c.pendingReason, reasonInvalidation)
+	c.pendingReason = ""
+	return
+}
+
+// Close stops periodic reloading, unsubscribes from invalidation messages, stops pending
+// aggregation timers and unregisters metrics. When a reload is running, Close waits
+// until it finishes or until `ctx` is done (the context error is returned in that case).
+// All reload attempts after Close are no-ops; data loaded so far remain readable.
+// Calling Close more than once is safe.
+func (c *Cache[K, T]) Close(ctx context.Context) (err error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.closed = true
+	reloadDone := c.reloadDone
+	// no more reloads will be started
+	c.releaseWaitersLocked(c.reloadSeq+1, ErrClosed)
+	c.mu.Unlock()
+
+	c.log.Debug().Msg("closing cache")
+
+	for _, source := range c.invalidationSources {
+		source.Stop()
+	}
+
+	if reloadDone != nil {
+		select {
+		case <-reloadDone:
+		case <-ctx.Done():
+			err = ctx.Err()
+			c.log.Warn().Err(err).Msg("running reload not finished before close")
+		}
+	}
+
+	// wait until last snapshot is written
+	c.persistWg.Wait()
+
+	// stops periodic reload goroutine, aggregation timers and running loads
+	c.cancel()
+
+	for _, closeFunc := range c.closeFuncs {
+		closeFunc()
+	}
+
+	c.subscribersMu.Lock()
+	c.subscribers = nil
+	c.reloadListeners = nil
+	c.subscribersMu.Unlock()
+
+	if c.metrics != nil {
+		c.metrics.Unregister()
+	}
+
+	return
+}
+
+func (c *Cache[K, T]) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.closed
+}
+
+// initInvalidations starts all invalidation sources. When any source fails to start,
+// already started sources are stopped.
+func (c *Cache[K, T]) initInvalidations() error {
+	if len(c.invalidationSources) == 0 {
+		c.log.Warn().Msg("invalidations are disabled")
+		return nil
+	}
+
+	for i, source := range c.invalidationSources {
+		err := source.Start(c.ctx, c.invalidationHandler(source))
+		if err != nil {
+			for _, started := range c.invalidationSources[:i] {
+				started.Stop()
+			}
+			return fmt.Errorf("cannot start invalidation source: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// invalidationHandler returns function handling events emitted by `source` and counting them in metrics
+func (c *Cache[K, T]) invalidationHandler(source InvalidationSource[K]) func(InvalidationEvent[K]) {
+	name := invalidationSourceName(source)
+	_, nats := source.(*NatsSource[K])
+
+	return func(event InvalidationEvent[K]) {
+		if c.metrics != nil {
+			c.metrics.ReceivedInvalidations.WithLabelValues(name).Inc()
+			if nats {
 				c.metrics.ReceivedNatsInvalidations.Inc()
 			}
-			c.InvalidateAll()
-		})
+		}
+
+		c.handleInvalidation(event)
 	}
 }
 
-func (c *Cache[K, T]) initPeriodicReload() {
-	baseInterval := c.timeouts.ReloadInterval
-	if baseInterval == 0 {
-		c.log.Warn().Msg("periodic reload disabled")
+// handleInvalidation invalidates keys from invalidation event or whole repository when
+// the event has no keys. Span propagated in event metadata becomes parent of the following reload.
+func (c *Cache[K, T]) handleInvalidation(event InvalidationEvent[K]) {
+	if c.hasEventVersion(event) {
+		c.log.Trace().Msg("Invalidation of already loaded version ignored")
 		return
 	}
 
-	// reload already performed, we will set nextReload in future
-	nextReloadTime := time.Now().Add(utils.RandomizeDuration(baseInterval, c.timeouts.Ranomizer))
-	c.nextReload = &nextReloadTime
+	c.addPendingSpan(event.Metadata)
 
+	if len(event.Keys) > 0 && c.loadByKeysFunc != nil {
+		c.log.Trace().Int("keys", len(event.Keys)).Msg("Invalidate keys")
+		c.invalidateKeys(reasonInvalidation, event.Keys)
+		return
+	}
+
+	// invalidate whole repository
+	c.log.Trace().Msg("Invalidate")
+	c.invalidateAll(reasonInvalidation)
+}
+
+// hasEventVersion returns true when versioned cache already holds items of version reported
+// in event metadata (see `VersionProbeSource`)
+func (c *Cache[K, T]) hasEventVersion(event InvalidationEvent[K]) bool {
+	if !c.versioned || len(event.Keys) > 0 {
+		return false
+	}
+
+	version, err := strconv.ParseUint(event.Metadata[MetadataVersion], 10, 64)
+	if err != nil {
+		return false
+	}
+
+	return Version(version) <= c.Version()
+}
+
+func (c *Cache[K, T]) initPeriodicReload() {
+	if c.timeouts.ReloadInterval == 0 {
+		c.log.Warn().Msg("periodic reload disabled")
+	}
+
+	// reload already performed and nextReload set by it
 	// start goroutine for automatic reloading
 	// when another reload occures, it will send message to reloadChan, which
 	// will stop the timer and start new one
+	// (no timer is running when next reload is not planned, e.g. periodic reload is disabled,
+	// nor while another reload is running - it plans the next one and notifies reloadChan when finished)
 	go func() {
 		for {
+			var timer *time.Timer
+			var timerChan <-chan time.Time
+
 			c.mu.Lock()
-			duration := time.Until(*c.nextReload)
+			if c.nextReload != nil && !c.isReloading {
+				timer = time.NewTimer(time.Until(*c.nextReload))
+				timerChan = timer.C
+			}
 			c.mu.Unlock()
 
-			timer := time.NewTimer(duration)
-
 			select {
-			case _, more := <-c.reloadChan:
-				timer.Stop()
-				if !more {
-					c.log.Debug().Msg("periodic reload stopped")
-					return
+			case <-c.reloadChan:
+				if timer != nil {
+					timer.Stop()
 				}
 
-			case <-timer.C:
-				_ = c.reload(false)
+			case <-timerChan:
+				_ = c.reload(reasonPeriodic)
 
 			case <-c.ctx.Done():
-				close(c.reloadChan)
+				if timer != nil {
+					timer.Stop()
+				}
+				c.log.Debug().Msg("periodic reload stopped")
+				return
 			}
 		}
 	}()
 }
 
-func (c *Cache[K, T]) setLoading(start time.Time, force bool) error {
+// ErrClosed is returned when cache has been already closed.
+var ErrClosed = errors.New("cache closed")
+
+// errLoadSuperseded cancels running load when invalidation is received during it
+// (see `Params.RestartOnInvalidation`)
+var errLoadSuperseded = errors.New("load superseded by invalidation")
+
+func (c *Cache[K, T]) setLoading(start time.Time, reason reloadReason) (seq uint64, err error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if c.closed {
+		return 0, ErrClosed
+	}
+
+	force := reason != reasonPeriodic
 	if c.isReloading {
-		return errors.New("already reloading")
+		if force {
+			// invalidation could be committed after running load started, so it could miss it
+			c.reloadRequested = mergeReasons(c.reloadRequested, reason)
+			if c.metrics != nil {
+				c.metrics.CoalescedInvalidations.Inc()
+			}
+			// initial load is never cancelled (there are no items to keep meanwhile)
+			if c.restartOnInvalidation && c.cancelLoad != nil && c.data.Load() != nil {
+				c.cancelLoad(errLoadSuperseded)
+			}
+		}
+		return 0, errors.New("already reloading")
 	}
 
 	if !force && (c.nextReload != nil && start.Before(*c.nextReload)) {
-		return errors.New("cannot reload yet")
+		return 0, errors.New("cannot reload yet")
 	}
 
 	c.isReloading = true
-	return nil
+	c.reloadDone = make(chan struct{})
+	c.reloadSeq++
+	return c.reloadSeq, nil
+}
+
+// reloadReason describes what triggered reload (used as metrics label)
+type reloadReason string
+
+const (
+	reasonInitial      reloadReason = "initial"
+	reasonPeriodic     reloadReason = "periodic"
+	reasonInvalidation reloadReason = "invalidation"
+	reasonManual       reloadReason = "manual"
+)
+
+// mergeReasons returns reason of one reload performed for both reasons
+// (reload requested by API call takes precedence over other reasons)
+func mergeReasons(a, b reloadReason) reloadReason {
+	if a == "" || b == reasonManual {
+		return b
+	}
+	return a
 }
 
 // reloads cache and sets reload timer
-func (c *Cache[K, T]) reload(force bool) (err error) {
+func (c *Cache[K, T]) reload(reason reloadReason) (err error) {
+	return c.reloadContext(c.ctx, reason)
+}
+
+// reloadContext reloads cache using `ctx` for loading data. Only periodic reload
+// can be skipped because its time has not come yet.
+func (c *Cache[K, T]) reloadContext(ctx context.Context, reason reloadReason) (err error) {
 	start := time.Now()
-	err = c.setLoading(start, force)
+	force := reason != reasonPeriodic
+	seq, err := c.setLoading(start, reason)
 	if err != nil {
 		c.log.Trace().Err(err).Msg("reload skipped")
 		return
 	}
 
-	c.log.Debug().Msg("loading started")
+	// only invalidated keys are reloaded when nothing else was invalidated
+	// (periodic reload always reloads everything)
+	keys := c.takePendingKeys(force)
+	full := keys == nil && c.isFullReloadNeeded(start)
+	c.log.Debug().Str("reason", string(reason)).Bool("full", full).Int("keys", len(keys)).Msg("loading started")
 
-	entries, err := c.loadAllFunc(c.ctx)
-	if err == nil {
-		c.data.Store(entries)
+	spanCtx, span := c.startReloadSpan(ctx, reason)
+	loadCtx, state, cancelLoad := c.loadContext(spanCtx)
+	defer cancelLoad(nil)
+	c.mu.Lock()
+	c.cancelLoad = cancelLoad
+	c.mu.Unlock()
+
+	var data *dataset[K, T]
+	changed := true
+	switch {
+	case keys != nil:
+		data, err = c.loadKeys(loadCtx, keys)
+	case full:
+		data, err = c.loadAll(loadCtx)
+	default:
+		data, changed, err = c.loadChanges(loadCtx)
+	}
 
-		if c.memSizeEnabled {
-			go c.updateMemSize()
+	// current items are kept when data source reports no change
+	notModified := false
+	if errors.Is(err, ErrNotModified) {
+		notModified, err = c.notModified()
+		if notModified {
+			data = c.loadData()
 		}
+	}
 
-	} else {
+	// loaded items are outdated when invalidation was received during load
+	superseded := errors.Is(context.Cause(loadCtx), errLoadSuperseded)
+	if superseded {
+		data, err = nil, errLoadSuperseded
+		if c.metrics != nil {
+			c.metrics.CancelledLoadCount.Inc()
+		}
+	} else if err != nil && errors.Is(loadCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		err = fmt.Errorf("load timed out after %s: %w", c.timeouts.LoadTimeout, err)
+		if c.metrics != nil {
+			c.metrics.TimedOutLoadCount.Inc()
+		}
+	}
+
+	if err != nil && keys != nil {
+		// invalidated keys will be loaded by the next reload
+		c.addPendingKeys(keys)
+	}
+	if err == nil {
+		if !notModified {
+			c.install(data, changed)
+		}
+		state.notifyInstalled()
+	}
+
+	if full && err == nil {
+		c.mu.Lock()
+		c.lastFullReload = start
+		c.mu.Unlock()
+	}
+
+	switch {
+	case superseded:
+		c.log.Debug().Msg("loading cancelled due to invalidation received during load")
+	case err != nil:
 		c.log.Warn().
 			Err(err).
 			Float64("duration_s", time.Since(start).Round(time.Millisecond).Seconds()).
 			Msg("loading failed")
 	}
 
+	result := loadResult(err, superseded)
+	if notModified && !superseded {
+		result = metrics_pkg.ResultNotModified
+	}
 	if c.metrics != nil {
-		c.metrics.LoadCount.Inc()
+		c.updateLoadMetrics(reason, start, result)
 	}
 
-	logEvent := c.log.Debug()
+	logEvent := c.log.Debug().Bool("not_modified", notModified)
 
-	var newNextReloadTime *time.Time
+	c.mu.Lock()
+	switch {
+	case superseded:
+		// restarted immediately, not a failure
+	case err != nil:
+		c.consecutiveFailures++
+		c.lastAttempt = start
+		c.lastErr = err
+	default:
+		c.consecutiveFailures = 0
+		c.lastAttempt = start
+		c.lastSuccess = start
+		c.lastErr = nil
+	}
+	failures := c.consecutiveFailures
+	c.mu.Unlock()
+
+	// periodic reload is planned since start of this reload, retry since its end
+	nextReloadBase := start
+	var nextReloadDelay time.Duration
 	if c.timeouts.ReloadInterval > 0 {
-		t := start.Add(utils.RandomizeDuration(c.timeouts.ReloadInterval, c.timeouts.Ranomizer))
+		nextReloadDelay = utils.RandomizeDuration(c.timeouts.ReloadInterval, c.timeouts.Ranomizer)
+	}
+	if err != nil && !superseded {
+		retryDelay := c.timeouts.Retry.backoff(failures)
+		if retryDelay == 0 && c.stale.Load() {
+			// stale data are served, try to load real data sooner
+			retryDelay = utils.RandomizeDuration(staleRetryInterval, c.timeouts.Ranomizer)
+		}
+
+		if retryDelay > 0 && (nextReloadDelay == 0 || retryDelay < nextReloadDelay) {
+			nextReloadDelay = retryDelay
+			nextReloadBase = time.Now()
+			logEvent = logEvent.Int("failures", failures)
+		}
+	}
+
+	var newNextReloadTime *time.Time
+	if nextReloadDelay > 0 {
+		t := nextReloadBase.Add(nextReloadDelay)
 		newNextReloadTime = &t
 		logEvent = logEvent.Time("next_reload", t)
 	}
@@ -234,28 +696,151 @@ func (c *Cache[K, T]) reload(force bool) (err error) {
 	// critical section start
 	c.mu.Lock()
 	c.isReloading = false
-	if newNextReloadTime != nil {
-		c.nextReload = newNextReloadTime
+	close(c.reloadDone)
+	c.reloadDone = nil
+	c.cancelLoad = nil
+	c.nextReload = newNextReloadTime
+	followUp := c.reloadRequested
+	if c.closed {
+		followUp = ""
+	}
+	c.reloadRequested = ""
+	if !superseded {
+		// waiters of superseded load are released by the following reload
+		c.releaseWaitersLocked(seq, err)
 	}
 	c.mu.Unlock()
 	// critical section end
 
+	if data != nil {
+		logEvent = logEvent.Int("count", len(data.entries))
+		if c.versioned {
+			logEvent = logEvent.Uint64("version", uint64(data.version))
+		}
+	}
 	logEvent.
-		Int("count", len(entries)).
 		Float64("duration_s", time.Since(start).Round(time.Millisecond).Seconds()).
 		Msg("loading finished")
-	if c.metrics != nil {
-		c.metrics.ItemsCount.Set(float64(len(entries)))
+	if c.metrics != nil && err == nil {
+		c.metrics.ItemsCount.Set(float64(len(data.entries)))
 	}
+	c.endReloadSpan(span, start, data, result, err)
 
 	// notify periodic reload goroutine that next reload time changed
-	if newNextReloadTime != nil {
-		c.reloadChan <- true
+	// (when notification is already pending, there is no need to send another one)
+	select {
+	case c.reloadChan <- true:
+	default:
+	}
+
+	// exactly one reload for all invalidations received during this reload
+	if followUp != "" {
+		c.log.Debug().Str("reason", string(followUp)).Msg("reloading again due to invalidation received during reload")
+		go c.reload(followUp)
 	}
 
 	return
 }
 
+// loadContext returns context for one load limited by `Timeouts.LoadTimeout`
+func (c *Cache[K, T]) loadContext(ctx context.Context) (context.Context, *loadState, context.CancelCauseFunc) {
+	ctx, state := c.withLoadState(ctx)
+	ctx, cancel := context.WithCancelCause(ctx)
+	if c.timeouts.LoadTimeout <= 0 {
+		return ctx, state, cancel
+	}
+
+	ctx, cancelTimeout := context.WithTimeout(ctx, c.timeouts.LoadTimeout)
+	return ctx, state, func(cause error) {
+		cancel(cause)
+		cancelTimeout()
+	}
+}
+
+// loadAll loads all items and creates new dataset from them
+func (c *Cache[K, T]) loadAll(ctx context.Context) (data *dataset[K, T], err error) {
+	entries, version, err := c.loadAllFunc(ctx)
+	if err != nil {
+		return
+	}
+
+	// version of loaded changes is kept when all items are not versioned,
+	// the next changes are loaded since it
+	if current, loaded := c.data.Load().(*dataset[K, T]); loaded && !c.versioned {
+		version = current.version
+	}
+
+	return c.buildDataset(entries, version)
+}
+
+// buildDataset validates loaded items and creates new dataset from them
+func (c *Cache[K, T]) buildDataset(entries map[K]*T, version Version) (data *dataset[K, T], err error) {
+	err = c.validate(entries)
+	if err != nil {
+		return
+	}
+
+	return newDataset(entries, version, c.indexes)
+}
+
+// install replaces current data by `data`. When items are not `changed` (only version changed),
+// nothing but the data is updated.
+func (c *Cache[K, T]) install(data *dataset[K, T], changed bool) {
+	oldData, _ := c.data.Swap(data).(*dataset[K, T])
+	if c.stale.Swap(false) {
+		c.log.Info().Msg("cache is not stale anymore")
+	}
+
+	if !changed {
+		return
+	}
+
+	if c.memSizeEnabled {
+		go c.updateMemSize()
+	}
+
+	if c.snapshotStore != nil {
+		c.persistWg.Add(1)
+		go c.saveSnapshot()
+	}
+
+	c.notifySubscribers(oldData, data)
+	c.notifyReloadListeners()
+}
+
+// notModified checks whether current items can be kept after load returned `ErrNotModified`
+func (c *Cache[K, T]) notModified() (bool, error) {
+	if c.data.Load() == nil || c.stale.Load() {
+		return false, fmt.Errorf("no loaded items to keep: %w", ErrNotModified)
+	}
+
+	return true, nil
+}
+
+// loadResult returns result of load used in metrics and traces
+func loadResult(err error, superseded bool) string {
+	switch {
+	case superseded:
+		return metrics_pkg.ResultCancelled
+	case err != nil:
+		return metrics_pkg.ResultFailure
+	default:
+		return metrics_pkg.ResultSuccess
+	}
+}
+
+func (c *Cache[K, T]) updateLoadMetrics(reason reloadReason, start time.Time, result string) {
+	switch result {
+	case metrics_pkg.ResultFailure:
+		c.metrics.LoadFailureCount.WithLabelValues(string(reason)).Inc()
+	case metrics_pkg.ResultSuccess, metrics_pkg.ResultNotModified:
+		c.metrics.LastSuccessTimestamp.Set(float64(start.UnixNano()) / float64(time.Second))
+	}
+
+	c.metrics.LoadCount.Inc()
+	c.metrics.LoadDuration.WithLabelValues(string(reason), result).Observe(time.Since(start).Seconds())
+}
+
 func (c *Cache[K, T]) updateMemSize() {
 	// handle potential panic (calculating size should not affect running app)
 	defer func() {
@@ -267,7 +852,7 @@ func (c *Cache[K, T]) updateMemSize() {
 		}
 	}()
 
-	entries := c.GetAll()
+	entries := c.loadData().entries
 
 	// c.log.Trace().
 	// 	Int("entries_count", len(entries)).
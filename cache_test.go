@@ -2,12 +2,23 @@ package codebook
 
 import (
 	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	cadre_metrics "github.com/moderntv/cadre/metrics"
 	"github.com/moderntv/codebook-cache/internal/test_utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 func TestCache(t *testing.T) {
@@ -17,8 +28,19 @@ func TestCache(t *testing.T) {
 	t.Run("testCacheInvalidateWithggregator", testCacheInvalidateWithggregator)
 	t.Run("testCacheInvalidateAllSpeed", testCacheInvalidateAllSpeed)
 	t.Run("testCacheMetrics", testCacheMetrics)
+	t.Run("testCacheLoadMetrics", testCacheLoadMetrics)
+	t.Run("testCacheReloadReasons", testCacheReloadReasons)
+	t.Run("testCacheMetricsRegisterer", testCacheMetricsRegisterer)
 	t.Run("testCacheMemsizeCalculated", testCacheMemsizeCalculated)
 	t.Run("TestCacheMemsizeManual", TestCacheMemsizeManual)
+	t.Run("testCacheClose", testCacheClose)
+	t.Run("testCacheCloseWaitsForReload", testCacheCloseWaitsForReload)
+	t.Run("testCacheInvalidateDuringReload", testCacheInvalidateDuringReload)
+	t.Run("testCacheRetry", testCacheRetry)
+	t.Run("testCacheLoadTimeout", testCacheLoadTimeout)
+	t.Run("testCachePeriodicReloadWaits", testCachePeriodicReloadWaits)
+	t.Run("testCacheRestartOnInvalidation", testCacheRestartOnInvalidation)
+	t.Run("testCacheNotModified", testCacheNotModified)
 }
 
 func testCacheGet(t *testing.T) {
@@ -411,3 +433,596 @@ func testCacheMetrics(t *testing.T) {
 	assert.Equal(t, test_utils.IntPointer(5000), c.Get("key5"))
 	assert.Nil(t, c.Get("key0"))
 }
+
+func testCacheLoadMetrics(t *testing.T) {
+	t.Parallel()
+
+	var fail atomic.Bool
+
+	start := time.Now()
+	c, err := New(Params[string, int]{
+		Context:         context.Background(),
+		Log:             test_utils.Logger(),
+		MetricsRegistry: test_utils.Metrics("testing_cache"),
+		Name:            "testing_cache",
+		LoadAllFunc: func(ctx context.Context) (map[string]*int, error) {
+			if fail.Load() {
+				return nil, errors.New("database is down")
+			}
+
+			return map[string]*int{
+				"key1": test_utils.IntPointer(1),
+			}, nil
+		},
+		Timeouts: Timeouts{
+			ReloadInterval: 10 * time.Second,
+		},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, float64(10), testutil.ToFloat64(c.metrics.ReloadInterval))
+	lastSuccess := testutil.ToFloat64(c.metrics.LastSuccessTimestamp)
+	assert.InDelta(t, float64(start.Unix()), lastSuccess, 1)
+
+	fail.Store(true)
+	assert.Error(t, c.reload(reasonManual))
+	assert.Error(t, c.reload(reasonInvalidation))
+	assert.Error(t, c.reload(reasonInvalidation))
+
+	assert.Equal(t, float64(4), testutil.ToFloat64(c.metrics.LoadCount))
+	assert.Equal(t, float64(1), testutil.ToFloat64(c.metrics.LoadFailureCount.WithLabelValues("manual")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(c.metrics.LoadFailureCount.WithLabelValues("invalidation")))
+	assert.Equal(t, lastSuccess, testutil.ToFloat64(c.metrics.LastSuccessTimestamp))
+	// initial/success, manual/failure and invalidation/failure
+	assert.Equal(t, 3, testutil.CollectAndCount(c.metrics.LoadDuration))
+
+	fail.Store(false)
+	assert.NoError(t, c.reload(reasonManual))
+	assert.Equal(t, 4, testutil.CollectAndCount(c.metrics.LoadDuration))
+	assert.GreaterOrEqual(t, testutil.ToFloat64(c.metrics.LastSuccessTimestamp), lastSuccess)
+}
+
+func testCacheReloadReasons(t *testing.T) {
+	t.Parallel()
+
+	// failing loads are counted per reason
+	var fail atomic.Bool
+	events := make(chan InvalidationEvent[string])
+	c, err := New(Params[string, int]{
+		Context:           context.Background(),
+		Log:               test_utils.Logger(),
+		MetricsRegisterer: prometheus.NewRegistry(),
+		Name:              "testing_cache",
+		LoadAllFunc: func(ctx context.Context) (map[string]*int, error) {
+			if fail.Load() {
+				return nil, errors.New("database is down")
+			}
+			return map[string]*int{
+				"key1": test_utils.IntPointer(1),
+			}, nil
+		},
+		LoadByKeysFunc: func(ctx context.Context, keys []string) (map[string]*int, error) {
+			return nil, errors.New("database is down")
+		},
+		Timeouts: Timeouts{
+			ReloadInterval: time.Minute,
+			ReloadDelay:    10 * time.Millisecond,
+		},
+		InvalidationSources: []InvalidationSource[string]{NewChannelSource(events)},
+	})
+	assert.NoError(t, err)
+	defer c.Close(context.Background())
+	fail.Store(true)
+
+	failures := func(reason reloadReason, count int) func() bool {
+		return func() bool {
+			return testutil.ToFloat64(c.metrics.LoadFailureCount.WithLabelValues(string(reason))) == float64(count)
+		}
+	}
+
+	// reloads triggered by API calls
+	c.InvalidateAll()
+	assert.Eventually(t, failures(reasonManual, 1), time.Second, 5*time.Millisecond)
+	c.InvalidateKeys("key1")
+	assert.Eventually(t, failures(reasonManual, 2), time.Second, 5*time.Millisecond)
+	assert.True(t, failures(reasonInvalidation, 0)())
+
+	// reloads triggered by invalidation source
+	events <- InvalidationEvent[string]{}
+	assert.Eventually(t, failures(reasonInvalidation, 1), time.Second, 5*time.Millisecond)
+	events <- InvalidationEvent[string]{Keys: []string{"key1"}}
+	assert.Eventually(t, failures(reasonInvalidation, 2), time.Second, 5*time.Millisecond)
+
+	// source invalidation aggregated with API call
+	events <- InvalidationEvent[string]{}
+	c.InvalidateAll()
+	assert.Eventually(t, failures(reasonManual, 3), time.Second, 5*time.Millisecond)
+	assert.True(t, failures(reasonInvalidation, 2)())
+}
+
+func testCacheMetricsRegisterer(t *testing.T) {
+	t.Parallel()
+
+	registry := prometheus.NewRegistry()
+	params := func(value int) Params[string, int] {
+		return Params[string, int]{
+			Context:           context.Background(),
+			Log:               test_utils.Logger(),
+			MetricsRegisterer: registry,
+			Name:              "testing_cache",
+			LoadAllFunc: func(ctx context.Context) (map[string]*int, error) {
+				entries := make(map[string]*int, value)
+				for i := 0; i < value; i++ {
+					entries[strconv.Itoa(i)] = test_utils.IntPointer(i)
+				}
+				return entries, nil
+			},
+		}
+	}
+
+	// caches with the same name are distinguished by cache_instance label
+	c1, err := New(params(1))
+	assert.NoError(t, err)
+	c2, err := New(params(2))
+	assert.NoError(t, err)
+	other, err := New(Params[string, int]{
+		Context:           context.Background(),
+		Log:               test_utils.Logger(),
+		MetricsRegisterer: registry,
+		Name:              "other_cache",
+		LoadAllFunc:       params(3).LoadAllFunc,
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(c1.metrics.ItemsCount))
+	assert.Equal(t, float64(2), testutil.ToFloat64(c2.metrics.ItemsCount))
+	assert.Equal(t, float64(3), testutil.ToFloat64(other.metrics.ItemsCount))
+	assert.Equal(t, float64(1), testutil.ToFloat64(c2.metrics.LoadCount))
+	assert.Equal(t, 3, testutil.CollectAndCount(registry, "codebook_cache_items_count"))
+
+	// metrics of a cache are removed when it is closed
+	assert.NoError(t, c1.Close(context.Background()))
+	assert.Equal(t, 2, testutil.CollectAndCount(registry, "codebook_cache_items_count"))
+	assert.Equal(t, float64(2), testutil.ToFloat64(c2.metrics.ItemsCount))
+	assert.NoError(t, c2.Close(context.Background()))
+	assert.Equal(t, 1, testutil.CollectAndCount(registry, "codebook_cache_items_count"))
+	assert.NoError(t, other.Close(context.Background()))
+	assert.Equal(t, 0, testutil.CollectAndCount(registry))
+
+	// metrics can be registered again
+	c1, err = New(params(1))
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1), testutil.ToFloat64(c1.metrics.ItemsCount))
+	assert.NoError(t, c1.Close(context.Background()))
+
+	_, err = New(Params[string, int]{
+		Context:           context.Background(),
+		Log:               test_utils.Logger(),
+		MetricsRegistry:   test_utils.Metrics("testing_cache"),
+		MetricsRegisterer: registry,
+		Name:              "testing_cache",
+		LoadAllFunc:       params(1).LoadAllFunc,
+	})
+	assert.Error(t, err)
+}
+
+func testCacheClose(t *testing.T) {
+	t.Parallel()
+
+	var loadCount atomic.Int64
+	nc := test_utils.NatsConnection(t)
+	registry := test_utils.Metrics("testing_cache")
+
+	params := Params[string, int]{
+		Context:         context.Background(),
+		Log:             test_utils.Logger(),
+		MetricsRegistry: registry,
+		Name:            "testing_cache",
+		LoadAllFunc: func(ctx context.Context) (map[string]*int, error) {
+			loadCount.Add(1)
+			return map[string]*int{
+				"key1": test_utils.IntPointer(1),
+			}, nil
+		},
+		Invalidations: &Invalidations{
+			Nats:   nc,
+			Prefix: "test.",
+			Messages: map[string]proto.Message{
+				"invalidate": &wrapperspb.StringValue{},
+			},
+		},
+		Timeouts: Timeouts{
+			ReloadInterval: 100 * time.Millisecond,
+			ReloadDelay:    50 * time.Millisecond,
+		},
+	}
+
+	c, err := New(params)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, nc.NumSubscriptions())
+
+	time.Sleep(250 * time.Millisecond)
+	assert.Greater(t, loadCount.Load(), int64(1))
+
+	err = c.Close(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, nc.NumSubscriptions())
+	_, err = registry.Get("cache_items_count")
+	assert.ErrorIs(t, err, cadre_metrics.ErrMetricNotFound)
+
+	// no reloads after close
+	loadsAfterClose := loadCount.Load()
+	c.InvalidateAll()
+	assert.Error(t, c.reload(reasonManual))
+	time.Sleep(250 * time.Millisecond)
+	assert.Equal(t, loadsAfterClose, loadCount.Load())
+
+	// data are still readable
+	assert.Equal(t, test_utils.IntPointer(1), c.Get("key1"))
+
+	// closing again is no-op
+	assert.NoError(t, c.Close(context.Background()))
+
+	// cache with the same name can be created again
+	c, err = New(params)
+	assert.NoError(t, err)
+	assert.NoError(t, c.Close(context.Background()))
+}
+
+func testCacheCloseWaitsForReload(t *testing.T) {
+	t.Parallel()
+
+	var loadDelay atomic.Int64
+	var loadFinished atomic.Bool
+
+	c, err := New(Params[string, int]{
+		Context: context.Background(),
+		Log:     test_utils.Logger(),
+		Name:    "testing_cache",
+		LoadAllFunc: func(ctx context.Context) (map[string]*int, error) {
+			time.Sleep(time.Duration(loadDelay.Load()))
+			loadFinished.Store(true)
+			return map[string]*int{}, nil
+		},
+		Timeouts: Timeouts{
+			ReloadInterval: 5 * time.Second,
+		},
+	})
+	assert.NoError(t, err)
+
+	loadDelay.Store(int64(300 * time.Millisecond))
+	loadFinished.Store(false)
+	c.InvalidateAll()
+	time.Sleep(50 * time.Millisecond)
+
+	assert.NoError(t, c.Close(context.Background()))
+	assert.True(t, loadFinished.Load())
+
+	// waiting is limited by context
+	c, err = New(Params[string, int]{
+		Context: context.Background(),
+		Log:     test_utils.Logger(),
+		Name:    "testing_cache",
+		LoadAllFunc: func(ctx context.Context) (map[string]*int, error) {
+			time.Sleep(time.Duration(loadDelay.Load()))
+			return map[string]*int{}, nil
+		},
+		Timeouts: Timeouts{
+			ReloadInterval: 5 * time.Second,
+		},
+	})
+	assert.NoError(t, err)
+
+	c.InvalidateAll()
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, c.Close(ctx), context.DeadlineExceeded)
+}
+
+func testCacheInvalidateDuringReload(t *testing.T) {
+	t.Parallel()
+
+	var loadCount atomic.Int64
+	var loadDelay atomic.Int64
+
+	c, err := New(Params[string, int]{
+		Context:         context.Background(),
+		Log:             test_utils.Logger(),
+		MetricsRegistry: test_utils.Metrics("testing_cache"),
+		Name:            "testing_cache",
+		LoadAllFunc: func(ctx context.Context) (map[string]*int, error) {
+			time.Sleep(time.Duration(loadDelay.Load()))
+			return map[string]*int{
+				"key1": test_utils.IntPointer(int(loadCount.Add(1))),
+			}, nil
+		},
+		Timeouts: Timeouts{
+			ReloadInterval: 10 * time.Second,
+			ReloadDelay:    0,
+		},
+	})
+	assert.NoError(t, err)
+
+	loadDelay.Store(int64(300 * time.Millisecond))
+	c.InvalidateAll()
+	time.Sleep(100 * time.Millisecond)
+
+	// invalidations during running reload are coalesced into exactly one following reload
+	c.InvalidateAll()
+	c.InvalidateAll()
+	c.InvalidateAll()
+	time.Sleep(900 * time.Millisecond)
+
+	assert.Equal(t, int64(3), loadCount.Load())
+	assert.Equal(t, test_utils.IntPointer(3), c.Get("key1"))
+	assert.Equal(t, float64(3), testutil.ToFloat64(c.metrics.CoalescedInvalidations))
+}
+
+func testCacheRetry(t *testing.T) {
+	t.Parallel()
+
+	var loadCount atomic.Int64
+	var failures atomic.Int64
+	var mu sync.Mutex
+	var starts, ends []time.Time
+
+	c, err := New(Params[string, int]{
+		Context: context.Background(),
+		Log:     test_utils.Logger(),
+		Name:    "testing_cache",
+		LoadAllFunc: func(ctx context.Context) (map[string]*int, error) {
+			mu.Lock()
+			starts = append(starts, time.Now())
+			mu.Unlock()
+
+			count := loadCount.Add(1)
+			if failures.Add(-1) >= 0 {
+				// slow failing load, backoff must not be consumed by it
+				time.Sleep(150 * time.Millisecond)
+				mu.Lock()
+				ends = append(ends, time.Now())
+				mu.Unlock()
+				return nil, errors.New("database is down")
+			}
+
+			return map[string]*int{
+				"key1": test_utils.IntPointer(int(count)),
+			}, nil
+		},
+		Timeouts: Timeouts{
+			ReloadInterval: 10 * time.Second,
+			Retry: RetryPolicy{
+				InitialBackoff: 100 * time.Millisecond,
+				MaxAttempts:    3,
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	// failed reload is retried 100 ms and then 200 ms after it finished
+	failures.Store(2)
+	c.InvalidateAll()
+	assert.Eventually(t, func() bool {
+		value := c.Get("key1")
+		return value != nil && *value == 4
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(4), loadCount.Load())
+
+	mu.Lock()
+	// tolerance for timer precision, retry planned since the start of failed load would come ~150 ms earlier
+	tolerance := 50 * time.Millisecond
+	assert.Greater(t, starts[2].Sub(ends[0]), 100*time.Millisecond-tolerance)
+	assert.Greater(t, starts[3].Sub(ends[1]), 200*time.Millisecond-tolerance)
+	mu.Unlock()
+
+	// no retry after success
+	assert.Never(t, func() bool { return loadCount.Load() != 4 }, 500*time.Millisecond, 10*time.Millisecond)
+
+	// retries are limited by MaxAttempts
+	failures.Store(10)
+	c.InvalidateAll()
+	assert.Eventually(t, func() bool { return loadCount.Load() == 8 }, 5*time.Second, 10*time.Millisecond)
+	assert.Never(t, func() bool { return loadCount.Load() != 8 }, time.Second, 10*time.Millisecond)
+}
+
+func testCacheLoadTimeout(t *testing.T) {
+	t.Parallel()
+
+	var loadCount atomic.Int64
+	var hang atomic.Bool
+
+	c, err := New(Params[string, int]{
+		Context:         context.Background(),
+		Log:             test_utils.Logger(),
+		MetricsRegistry: test_utils.Metrics("testing_cache"),
+		Name:            "testing_cache",
+		LoadAllFunc: func(ctx context.Context) (map[string]*int, error) {
+			count := loadCount.Add(1)
+			if hang.Load() {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+
+			return map[string]*int{
+				"key1": test_utils.IntPointer(int(count)),
+			}, nil
+		},
+		Timeouts: Timeouts{
+			ReloadInterval: 10 * time.Second,
+			LoadTimeout:    100 * time.Millisecond,
+		},
+	})
+	assert.NoError(t, err)
+
+	// hung load is cancelled after timeout and does not block following reloads
+	hang.Store(true)
+	err = c.InvalidateAndWait(context.Background())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, test_utils.IntPointer(1), c.Get("key1"))
+	assert.Equal(t, float64(1), testutil.ToFloat64(c.metrics.TimedOutLoadCount))
+
+	hang.Store(false)
+	assert.NoError(t, c.InvalidateAndWait(context.Background()))
+	assert.Equal(t, test_utils.IntPointer(3), c.Get("key1"))
+	assert.Equal(t, float64(1), testutil.ToFloat64(c.metrics.TimedOutLoadCount))
+}
+
+// skipCounter counts log messages about skipped reloads
+type skipCounter struct {
+	count atomic.Int64
+}
+
+func (w *skipCounter) Write(p []byte) (int, error) {
+	if strings.Contains(string(p), "reload skipped") {
+		w.count.Add(1)
+	}
+	return len(p), nil
+}
+
+func testCachePeriodicReloadWaits(t *testing.T) {
+	t.Parallel()
+
+	var loadCount atomic.Int64
+	var slow atomic.Bool
+	skipped := &skipCounter{}
+	zerolog.SetGlobalLevel(zerolog.TraceLevel)
+
+	c, err := New(Params[string, int]{
+		Context: context.Background(),
+		Log:     zerolog.New(skipped),
+		Name:    "testing_cache",
+		LoadAllFunc: func(ctx context.Context) (map[string]*int, error) {
+			count := loadCount.Add(1)
+			if slow.Load() {
+				time.Sleep(time.Second)
+			}
+
+			return map[string]*int{
+				"key1": test_utils.IntPointer(int(count)),
+			}, nil
+		},
+		Timeouts: Timeouts{
+			ReloadInterval: 100 * time.Millisecond,
+		},
+	})
+	assert.NoError(t, err)
+	defer c.Close(context.Background())
+
+	// periodic reload waits for running reload instead of retrying it until it finishes
+	slow.Store(true)
+	go c.InvalidateAll()
+	time.Sleep(500 * time.Millisecond)
+	assert.Less(t, skipped.count.Load(), int64(10))
+
+	// periodic reloads continue after it
+	slow.Store(false)
+	count := loadCount.Load()
+	assert.Eventually(t, func() bool { return loadCount.Load() > count+1 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func testCacheRestartOnInvalidation(t *testing.T) {
+	t.Parallel()
+
+	var loadCount atomic.Int64
+	var loadDelay atomic.Int64
+
+	c, err := New(Params[string, int]{
+		Context:         context.Background(),
+		Log:             test_utils.Logger(),
+		MetricsRegistry: test_utils.Metrics("testing_cache"),
+		Name:            "testing_cache",
+		LoadAllFunc: func(ctx context.Context) (map[string]*int, error) {
+			count := loadCount.Add(1)
+			select {
+			case <-time.After(time.Duration(loadDelay.Load())):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+
+			return map[string]*int{
+				"key1": test_utils.IntPointer(int(count)),
+			}, nil
+		},
+		Timeouts: Timeouts{
+			ReloadInterval: 10 * time.Second,
+		},
+		RestartOnInvalidation: true,
+	})
+	assert.NoError(t, err)
+
+	loadDelay.Store(int64(300 * time.Millisecond))
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		c.InvalidateAll()
+	}()
+
+	// waiting for cancelled load continues until the restarted load finishes
+	assert.NoError(t, c.InvalidateAndWait(context.Background()))
+	assert.Equal(t, int64(3), loadCount.Load())
+	assert.Equal(t, test_utils.IntPointer(3), c.Get("key1"))
+	assert.Equal(t, float64(1), testutil.ToFloat64(c.metrics.CancelledLoadCount))
+}
+
+func testCacheNotModified(t *testing.T) {
+	t.Parallel()
+
+	var loadCount atomic.Int64
+	var notModified atomic.Bool
+	notModified.Store(true)
+
+	params := Params[string, int]{
+		Context:           context.Background(),
+		Log:               test_utils.Logger(),
+		MetricsRegisterer: prometheus.NewRegistry(),
+		Name:              "testing_cache",
+		LoadAllFunc: func(ctx context.Context) (map[string]*int, error) {
+			count := loadCount.Add(1)
+			if notModified.Load() {
+				return nil, ErrNotModified
+			}
+
+			return map[string]*int{
+				"key1": test_utils.IntPointer(int(count)),
+			}, nil
+		},
+		Timeouts: Timeouts{
+			ReloadInterval: 10 * time.Second,
+		},
+	}
+
+	// there are no items to keep during initial load
+	_, err := New(params)
+	assert.ErrorIs(t, err, ErrNotModified)
+
+	notModified.Store(false)
+	c, err := New(params)
+	assert.NoError(t, err)
+	defer c.Close(context.Background())
+
+	var changes atomic.Int64
+	c.Subscribe(func(Changes[string]) {
+		changes.Add(1)
+	})
+
+	entries := c.GetAll()
+	lastSuccess := c.Status().LastSuccess
+
+	// current items are kept
+	notModified.Store(true)
+	assert.NoError(t, c.InvalidateAndWait(context.Background()))
+	assert.Equal(t, test_utils.IntPointer(2), c.Get("key1"))
+	assert.Equal(t, reflect.ValueOf(entries).Pointer(), reflect.ValueOf(c.GetAll()).Pointer())
+	assert.Equal(t, int64(0), changes.Load())
+	assert.True(t, c.Status().LastSuccess.After(lastSuccess))
+	assert.NoError(t, c.Status().LastError)
+	assert.InDelta(t, float64(c.Status().LastSuccess.Unix()), testutil.ToFloat64(c.metrics.LastSuccessTimestamp), 1)
+	assert.Equal(t, float64(0), testutil.ToFloat64(c.metrics.LoadFailureCount.WithLabelValues("manual")))
+	assert.Equal(t, 2, testutil.CollectAndCount(c.metrics.LoadDuration))
+
+	notModified.Store(false)
+	assert.NoError(t, c.InvalidateAndWait(context.Background()))
+	assert.Equal(t, test_utils.IntPointer(4), c.Get("key1"))
+	assert.Equal(t, int64(1), changes.Load())
+}
@@ -0,0 +1,132 @@
+package codebook
+
+import (
+	"reflect"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// EqualFunc reports whether two versions of the same item are equal.
+type EqualFunc[T any] func(a, b *T) bool
+
+// Changes describes the difference between two consecutive sets of items in cache.
+type Changes[K comparable] struct {
+	Added    []K
+	Removed  []K
+	Modified []K
+}
+
+// Empty returns true when nothing has changed.
+func (ch Changes[K]) Empty() bool {
+	return len(ch.Added) == 0 && len(ch.Removed) == 0 && len(ch.Modified) == 0
+}
+
+// ChangeHandler is called after each successful reload which changed cached items.
+type ChangeHandler[K comparable] func(changes Changes[K])
+
+// DefaultEqual compares items using `proto.Equal` when `*T` is a proto message
+// and `reflect.DeepEqual` otherwise.
+func DefaultEqual[T any](a, b *T) bool {
+	if a == b {
+		return true
+	}
+	if a == nil || b == nil {
+		return false
+	}
+
+	aMsg, ok := any(a).(proto.Message)
+	if ok {
+		return proto.Equal(aMsg, any(b).(proto.Message))
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+func diffEntries[K comparable, T any](oldEntries, newEntries map[K]*T, equal EqualFunc[T]) (changes Changes[K]) {
+	for key, newEntry := range newEntries {
+		oldEntry, exists := oldEntries[key]
+		if !exists {
+			changes.Added = append(changes.Added, key)
+			continue
+		}
+
+		if oldEntry != newEntry && !equal(oldEntry, newEntry) {
+			changes.Modified = append(changes.Modified, key)
+		}
+	}
+
+	for key := range oldEntries {
+		if _, exists := newEntries[key]; !exists {
+			changes.Removed = append(changes.Removed, key)
+		}
+	}
+
+	return
+}
+
+// Subscribe registers `handler` which is called after each successful reload that changed
+// cached items (added, removed or modified keys). Handlers are called synchronously one
+// after another from the reloading goroutine, after the new data are visible to readers.
+// Returned function removes the subscription.
+func (c *Cache[K, T]) Subscribe(handler ChangeHandler[K]) (unsubscribe func()) {
+	c.subscribersMu.Lock()
+	defer c.subscribersMu.Unlock()
+
+	id := c.nextSubscriberID
+	c.nextSubscriberID++
+	if c.subscribers == nil {
+		c.subscribers = make(map[uint64]ChangeHandler[K])
+	}
+	c.subscribers[id] = handler
+
+	unsubscribe = func() {
+		c.subscribersMu.Lock()
+		delete(c.subscribers, id)
+		c.subscribersMu.Unlock()
+	}
+
+	return
+}
+
+func (c *Cache[K, T]) notifySubscribers(oldData, newData *dataset[K, T]) {
+	c.subscribersMu.Lock()
+	handlers := make([]ChangeHandler[K], 0, len(c.subscribers))
+	for _, handler := range c.subscribers {
+		handlers = append(handlers, handler)
+	}
+	c.subscribersMu.Unlock()
+
+	if len(handlers) == 0 || oldData == nil {
+		return
+	}
+
+	changes := diffEntries(oldData.entries, newData.entries, c.equalFunc)
+	if changes.Empty() {
+		c.log.Trace().Msg("no changes detected")
+		return
+	}
+
+	c.log.Debug().
+		Int("added", len(changes.Added)).
+		Int("removed", len(changes.Removed)).
+		Int("modified", len(changes.Modified)).
+		Msg("changes detected")
+
+	for _, handler := range handlers {
+		c.callChangeHandler(handler, changes)
+	}
+}
+
+func (c *Cache[K, T]) callChangeHandler(handler ChangeHandler[K], changes Changes[K]) {
+	// handle potential panic (subscriber should not affect reloading)
+	defer func() {
+		err := recover()
+		if err != nil {
+			c.log.Warn().
+				Interface("err", err).
+				Msg("panic occurred in change handler")
+		}
+	}()
+
+	handler(changes)
+}
@@ -0,0 +1,124 @@
+package codebook
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/moderntv/codebook-cache/internal/test_utils"
+)
+
+func TestChanges(t *testing.T) {
+	t.Run("testChangesDiff", testChangesDiff)
+	t.Run("testChangesDefaultEqual", testChangesDefaultEqual)
+	t.Run("testChangesSubscribe", testChangesSubscribe)
+}
+
+func testChangesDiff(t *testing.T) {
+	t.Parallel()
+
+	shared := test_utils.IntPointer(5)
+	oldEntries := map[string]*int{
+		"same":     test_utils.IntPointer(1),
+		"modified": test_utils.IntPointer(2),
+		"removed":  test_utils.IntPointer(3),
+		"shared":   shared,
+	}
+	newEntries := map[string]*int{
+		"same":     test_utils.IntPointer(1),
+		"modified": test_utils.IntPointer(20),
+		"added":    test_utils.IntPointer(4),
+		"shared":   shared,
+	}
+
+	changes := diffEntries(oldEntries, newEntries, DefaultEqual[int])
+	assert.Equal(t, []string{"added"}, changes.Added)
+	assert.Equal(t, []string{"removed"}, changes.Removed)
+	assert.Equal(t, []string{"modified"}, changes.Modified)
+	assert.False(t, changes.Empty())
+
+	changes = diffEntries(oldEntries, oldEntries, DefaultEqual[int])
+	assert.True(t, changes.Empty())
+}
+
+func testChangesDefaultEqual(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, DefaultEqual(wrapperspb.String("a"), wrapperspb.String("a")))
+	assert.False(t, DefaultEqual(wrapperspb.String("a"), wrapperspb.String("b")))
+	assert.False(t, DefaultEqual(wrapperspb.String("a"), nil))
+	assert.True(t, DefaultEqual(test_utils.IntPointer(1), test_utils.IntPointer(1)))
+	assert.False(t, DefaultEqual(test_utils.IntPointer(1), test_utils.IntPointer(2)))
+}
+
+func testChangesSubscribe(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	entries := map[string]*int{
+		"key1": test_utils.IntPointer(1),
+		"key2": test_utils.IntPointer(2),
+	}
+
+	c, err := New(Params[string, int]{
+		Context: context.Background(),
+		Log:     test_utils.Logger(),
+		Name:    "testing_cache",
+		LoadAllFunc: func(ctx context.Context) (map[string]*int, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			copied := make(map[string]*int, len(entries))
+			for key, value := range entries {
+				copied[key] = test_utils.IntPointer(*value)
+			}
+			return copied, nil
+		},
+		Timeouts: Timeouts{
+			ReloadInterval: 5 * time.Second,
+		},
+	})
+	assert.NoError(t, err)
+
+	changesChan := make(chan Changes[string], 10)
+	unsubscribe := c.Subscribe(func(changes Changes[string]) {
+		changesChan <- changes
+	})
+
+	// nothing changed -> no notification
+	assert.NoError(t, c.reload(reasonManual))
+	assert.Len(t, changesChan, 0)
+
+	mu.Lock()
+	entries["key1"] = test_utils.IntPointer(10)
+	entries["key3"] = test_utils.IntPointer(3)
+	delete(entries, "key2")
+	mu.Unlock()
+
+	assert.NoError(t, c.reload(reasonManual))
+	select {
+	case changes := <-changesChan:
+		assert.Equal(t, []string{"key3"}, changes.Added)
+		assert.Equal(t, []string{"key2"}, changes.Removed)
+		assert.Equal(t, []string{"key1"}, changes.Modified)
+		// new data are visible when handler is called
+		assert.Equal(t, test_utils.IntPointer(10), c.Get("key1"))
+
+	case <-time.After(time.Second):
+		t.Fatal("changes not received")
+	}
+
+	unsubscribe()
+
+	mu.Lock()
+	entries["key4"] = test_utils.IntPointer(4)
+	mu.Unlock()
+
+	assert.NoError(t, c.reload(reasonManual))
+	assert.Len(t, changesChan, 0)
+	assert.Equal(t, test_utils.IntPointer(4), c.Get("key4"))
+}
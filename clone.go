@@ -0,0 +1,96 @@
+package codebook
+
+import (
+	"reflect"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// CloneFunc returns deep copy of item.
+type CloneFunc[T any] func(item *T) *T
+
+// DefaultClone copies items using `proto.Clone` when `*T` is a proto message and by reflection
+// otherwise. Reflection copies exported fields deeply (pointers, slices, maps and interfaces
+// are copied recursively, nested proto messages by `proto.Clone`), unexported fields are copied
+// shallowly. Items must not contain reference cycles.
+func DefaultClone[T any](item *T) *T {
+	if item == nil {
+		return nil
+	}
+
+	msg, ok := any(item).(proto.Message)
+	if ok {
+		return any(proto.Clone(msg)).(*T)
+	}
+
+	return deepCopy(reflect.ValueOf(item)).Interface().(*T)
+}
+
+func deepCopy(src reflect.Value) reflect.Value {
+	switch src.Kind() {
+	case reflect.Pointer:
+		if src.IsNil() {
+			return reflect.Zero(src.Type())
+		}
+		if msg, ok := src.Interface().(proto.Message); ok {
+			return reflect.ValueOf(proto.Clone(msg))
+		}
+
+		dst := reflect.New(src.Type().Elem())
+		dst.Elem().Set(deepCopy(src.Elem()))
+		return dst
+
+	case reflect.Struct:
+		dst := reflect.New(src.Type()).Elem()
+		dst.Set(src)
+		for i := 0; i < dst.NumField(); i++ {
+			if dst.Field(i).CanSet() {
+				dst.Field(i).Set(deepCopy(src.Field(i)))
+			}
+		}
+		return dst
+
+	case reflect.Slice:
+		if src.IsNil() {
+			return reflect.Zero(src.Type())
+		}
+
+		dst := reflect.MakeSlice(src.Type(), src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			dst.Index(i).Set(deepCopy(src.Index(i)))
+		}
+		return dst
+
+	case reflect.Array:
+		dst := reflect.New(src.Type()).Elem()
+		for i := 0; i < src.Len(); i++ {
+			dst.Index(i).Set(deepCopy(src.Index(i)))
+		}
+		return dst
+
+	case reflect.Map:
+		if src.IsNil() {
+			return reflect.Zero(src.Type())
+		}
+
+		dst := reflect.MakeMapWithSize(src.Type(), src.Len())
+		iter := src.MapRange()
+		for iter.Next() {
+			dst.SetMapIndex(deepCopy(iter.Key()), deepCopy(iter.Value()))
+		}
+		return dst
+
+	case reflect.Interface:
+		if src.IsNil() {
+			return reflect.Zero(src.Type())
+		}
+
+		dst := reflect.New(src.Type()).Elem()
+		dst.Set(deepCopy(src.Elem()))
+		return dst
+
+	default:
+		// values without references (channels and functions are shared)
+		return src
+	}
+}
@@ -0,0 +1,158 @@
+package codebook
+
+import (
+	"bufio"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec serializes complete set of cached items (used by `FileSnapshotStore`).
+type Codec[K comparable, T any] interface {
+	Encode(w io.Writer, entries map[K]*T) error
+	Decode(r io.Reader) (map[K]*T, error)
+}
+
+// maxProtoMessageSize limits size of one message read by proto codec (protobuf messages cannot exceed 2 GiB)
+const maxProtoMessageSize = 1<<31 - 1
+
+type gobCodec[K comparable, T any] struct{}
+
+// NewGobCodec creates codec using `encoding/gob`. Only exported fields of `T` are serialized.
+func NewGobCodec[K comparable, T any]() Codec[K, T] {
+	return gobCodec[K, T]{}
+}
+
+func (gobCodec[K, T]) Encode(w io.Writer, entries map[K]*T) error {
+	return gob.NewEncoder(w).Encode(entries)
+}
+
+func (gobCodec[K, T]) Decode(r io.Reader) (entries map[K]*T, err error) {
+	err = gob.NewDecoder(r).Decode(&entries)
+	return
+}
+
+type jsonCodec[K comparable, T any] struct{}
+
+// NewJSONCodec creates codec using `encoding/json`. Key type `K` must be supported
+// as JSON object key (string, integer or `encoding.TextMarshaler`).
+func NewJSONCodec[K comparable, T any]() Codec[K, T] {
+	return jsonCodec[K, T]{}
+}
+
+func (jsonCodec[K, T]) Encode(w io.Writer, entries map[K]*T) error {
+	return json.NewEncoder(w).Encode(entries)
+}
+
+func (jsonCodec[K, T]) Decode(r io.Reader) (entries map[K]*T, err error) {
+	err = json.NewDecoder(r).Decode(&entries)
+	return
+}
+
+type protoCodec[K comparable, T any] struct {
+	keyFunc func(entry *T) K
+}
+
+// NewProtoCodec creates codec for caches with proto message values (`*T` must implement `proto.Message`).
+// Items are stored as a sequence of length-delimited proto messages and keys are derived
+// back from decoded items by `keyFunc`.
+func NewProtoCodec[K comparable, T any](keyFunc func(entry *T) K) Codec[K, T] {
+	return protoCodec[K, T]{
+		keyFunc: keyFunc,
+	}
+}
+
+func (c protoCodec[K, T]) Encode(w io.Writer, entries map[K]*T) (err error) {
+	bw := bufio.NewWriter(w)
+	var buf []byte
+	for _, entry := range entries {
+		msg, ok := any(entry).(proto.Message)
+		if !ok {
+			return fmt.Errorf("%T is not a proto message", entry)
+		}
+
+		buf, err = proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+		if err != nil {
+			return
+		}
+
+		_, err = bw.Write(protowire.AppendVarint(nil, uint64(len(buf))))
+		if err != nil {
+			return
+		}
+		_, err = bw.Write(buf)
+		if err != nil {
+			return
+		}
+	}
+
+	return bw.Flush()
+}
+
+func (c protoCodec[K, T]) Decode(r io.Reader) (entries map[K]*T, err error) {
+	br := bufio.NewReader(r)
+	entries = make(map[K]*T)
+	for {
+		var size uint64
+		size, err = readVarint(br)
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return
+		}
+
+		if size > maxProtoMessageSize {
+			return nil, fmt.Errorf("message size %d exceeds limit %d", size, maxProtoMessageSize)
+		}
+
+		// buffer grows only with data really read, so corrupted size does not allocate too much memory
+		var buf []byte
+		buf, err = io.ReadAll(io.LimitReader(br, int64(size)))
+		if err != nil {
+			return
+		}
+		if uint64(len(buf)) != size {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		entry := new(T)
+		msg, ok := any(entry).(proto.Message)
+		if !ok {
+			return nil, fmt.Errorf("%T is not a proto message", entry)
+		}
+
+		err = proto.Unmarshal(buf, msg)
+		if err != nil {
+			return
+		}
+
+		entries[c.keyFunc(entry)] = entry
+	}
+}
+
+// readVarint reads one protobuf varint. Returns `io.EOF` only when no byte was read.
+func readVarint(r io.ByteReader) (value uint64, err error) {
+	for shift := 0; shift < 64; shift += 7 {
+		var b byte
+		b, err = r.ReadByte()
+		if err != nil {
+			if shift > 0 && errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return
+		}
+
+		value |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			return
+		}
+	}
+
+	return 0, errors.New("varint overflow")
+}
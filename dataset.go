@@ -0,0 +1,44 @@
+package codebook
+
+// dataset holds one complete generation of loaded items together with everything
+// derived from them. It is never modified after it is stored into the cache,
+// so it can be read without locking.
+type dataset[K comparable, T any] struct {
+	entries map[K]*T
+	indexes map[string]any
+	version Version
+}
+
+func newDataset[K comparable, T any](entries map[K]*T, version Version, indexes []Index[K, T]) (data *dataset[K, T], err error) {
+	data = &dataset[K, T]{
+		entries: entries,
+		version: version,
+	}
+
+	if len(indexes) == 0 {
+		return
+	}
+
+	data.indexes = make(map[string]any, len(indexes))
+	for _, index := range indexes {
+		var built any
+		built, err = index.build(entries)
+		if err != nil {
+			data = nil
+			return
+		}
+
+		data.indexes[index.Name()] = built
+	}
+
+	return
+}
+
+// withVersion returns copy of dataset with the same items and different version.
+func (d *dataset[K, T]) withVersion(version Version) *dataset[K, T] {
+	return &dataset[K, T]{
+		entries: d.entries,
+		indexes: d.indexes,
+		version: version,
+	}
+}
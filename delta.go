@@ -0,0 +1,66 @@
+package codebook
+
+import (
+	"context"
+	"time"
+)
+
+// LoadChangesFunc loads changes made in data source after version `since`: inserted or updated
+// items (`upserts`) and keys of deleted items (`deletes`) together with the current version.
+// Without `Params.LoadAllVersionedFunc`, version of items is not known after the first full load,
+// so `since` is 0 in the first call.
+type LoadChangesFunc[K comparable, T any] func(ctx context.Context, since Version) (upserts map[K]*T, deletes []K, newVersion Version, err error)
+
+// isFullReloadNeeded decides whether all items or only changes should be loaded
+func (c *Cache[K, T]) isFullReloadNeeded(start time.Time) bool {
+	if c.loadChangesFunc == nil || c.stale.Load() {
+		return true
+	}
+
+	if _, loaded := c.data.Load().(*dataset[K, T]); !loaded {
+		return true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return start.Sub(c.lastFullReload) >= c.timeouts.FullReloadInterval
+}
+
+// loadChanges loads changes since the current version and applies them on copy of the current items
+// (the current items are never modified). `changed` is false when there are no changes.
+func (c *Cache[K, T]) loadChanges(ctx context.Context) (data *dataset[K, T], changed bool, err error) {
+	current := c.loadData()
+
+	upserts, deletes, version, err := c.loadChangesFunc(ctx, current.version)
+	if err != nil {
+		return
+	}
+
+	c.log.Trace().
+		Uint64("since", uint64(current.version)).
+		Uint64("version", uint64(version)).
+		Int("upserts", len(upserts)).
+		Int("deletes", len(deletes)).
+		Msg("changes loaded")
+
+	if len(upserts) == 0 && len(deletes) == 0 {
+		data = current.withVersion(version)
+		return
+	}
+
+	entries := make(map[K]*T, len(current.entries)+len(upserts))
+	for key, entry := range current.entries {
+		entries[key] = entry
+	}
+	for key, entry := range upserts {
+		entries[key] = entry
+	}
+	for _, key := range deletes {
+		delete(entries, key)
+	}
+
+	data, err = c.buildDataset(entries, version)
+	changed = true
+	return
+}
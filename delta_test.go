@@ -0,0 +1,242 @@
+package codebook
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moderntv/codebook-cache/internal/test_utils"
+)
+
+func TestDelta(t *testing.T) {
+	t.Run("testDeltaReload", testDeltaReload)
+	t.Run("testDeltaFullReload", testDeltaFullReload)
+	t.Run("testDeltaDefaultFullReload", testDeltaDefaultFullReload)
+	t.Run("testDeltaParams", testDeltaParams)
+}
+
+// versionedSource simulates versioned data source with log of changes
+type versionedSource struct {
+	mu        sync.Mutex
+	version   Version
+	entries   map[string]*int
+	changedAt map[string]Version // version of last upsert or delete of key
+	deleted   map[string]bool
+	fullLoads atomic.Int64
+	deltas    atomic.Int64
+}
+
+func newVersionedSource() *versionedSource {
+	return &versionedSource{
+		entries:   make(map[string]*int),
+		changedAt: make(map[string]Version),
+		deleted:   make(map[string]bool),
+	}
+}
+
+func (s *versionedSource) set(key string, value int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.version++
+	s.entries[key] = test_utils.IntPointer(value)
+	s.changedAt[key] = s.version
+	delete(s.deleted, key)
+}
+
+func (s *versionedSource) delete(key string, logged bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	if logged {
+		s.version++
+		s.changedAt[key] = s.version
+		s.deleted[key] = true
+	}
+}
+
+func (s *versionedSource) loadAll(ctx context.Context) (map[string]*int, Version, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fullLoads.Add(1)
+	entries := make(map[string]*int, len(s.entries))
+	for key, value := range s.entries {
+		entries[key] = value
+	}
+
+	return entries, s.version, nil
+}
+
+func (s *versionedSource) loadChanges(ctx context.Context, since Version) (map[string]*int, []string, Version, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deltas.Add(1)
+	upserts := make(map[string]*int)
+	deletes := make([]string, 0)
+	for key, version := range s.changedAt {
+		if version <= since {
+			continue
+		}
+
+		if s.deleted[key] {
+			deletes = append(deletes, key)
+		} else {
+			upserts[key] = s.entries[key]
+		}
+	}
+
+	return upserts, deletes, s.version, nil
+}
+
+func testDeltaReload(t *testing.T) {
+	t.Parallel()
+
+	source := newVersionedSource()
+	source.set("key1", 1)
+	source.set("key2", 2)
+
+	c, err := New(Params[string, int]{
+		Context:              context.Background(),
+		Log:                  test_utils.Logger(),
+		Name:                 "testing_cache",
+		LoadAllVersionedFunc: source.loadAll,
+		LoadChangesFunc:      source.loadChanges,
+		Timeouts: Timeouts{
+			ReloadInterval: 5 * time.Second,
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), source.fullLoads.Load())
+
+	before := c.GetAll()
+
+	source.set("key1", 10)
+	source.set("key3", 3)
+	source.delete("key2", true)
+	assert.NoError(t, c.reload(reasonManual))
+
+	assert.Equal(t, int64(1), source.fullLoads.Load())
+	assert.Equal(t, int64(1), source.deltas.Load())
+	assert.Equal(t, map[string]*int{
+		"key1": test_utils.IntPointer(10),
+		"key3": test_utils.IntPointer(3),
+	}, c.GetAll())
+	assert.Equal(t, Version(5), c.loadData().version)
+
+	// previously returned items are not modified
+	assert.Equal(t, map[string]*int{
+		"key1": test_utils.IntPointer(1),
+		"key2": test_utils.IntPointer(2),
+	}, before)
+
+	// no changes -> the same items
+	before = c.GetAll()
+	assert.NoError(t, c.reload(reasonManual))
+	assert.Equal(t, int64(2), source.deltas.Load())
+	after := c.GetAll()
+	assert.Equal(t, before, after)
+	assert.Equal(t, len(before), len(after))
+}
+
+func testDeltaFullReload(t *testing.T) {
+	t.Parallel()
+
+	source := newVersionedSource()
+	source.set("key1", 1)
+	source.set("key2", 2)
+
+	c, err := New(Params[string, int]{
+		Context:              context.Background(),
+		Log:                  test_utils.Logger(),
+		Name:                 "testing_cache",
+		LoadAllVersionedFunc: source.loadAll,
+		LoadChangesFunc:      source.loadChanges,
+		Timeouts: Timeouts{
+			ReloadInterval:     5 * time.Second,
+			FullReloadInterval: 300 * time.Millisecond,
+		},
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, c.reload(reasonManual))
+
+	// deletion not visible in changes -> drift
+	source.delete("key2", false)
+	assert.NoError(t, c.reload(reasonManual))
+	assert.Len(t, c.GetAll(), 2)
+
+	// drift healed by full reload
+	time.Sleep(350 * time.Millisecond)
+	assert.NoError(t, c.reload(reasonManual))
+	assert.Equal(t, int64(2), source.fullLoads.Load())
+	assert.Equal(t, int64(2), source.deltas.Load())
+	assert.Len(t, c.GetAll(), 1)
+	assert.Nil(t, c.Get("key2"))
+}
+
+func testDeltaDefaultFullReload(t *testing.T) {
+	t.Parallel()
+
+	source := newVersionedSource()
+	source.set("key1", 1)
+	source.set("key2", 2)
+
+	c, err := New(Params[string, int]{
+		Context:              context.Background(),
+		Log:                  test_utils.Logger(),
+		Name:                 "testing_cache",
+		LoadAllVersionedFunc: source.loadAll,
+		LoadChangesFunc:      source.loadChanges,
+		Timeouts: Timeouts{
+			ReloadInterval: 5 * time.Second,
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, defaultFullReloadInterval, c.timeouts.FullReloadInterval)
+
+	// deletion not visible in changes -> drift
+	source.delete("key2", false)
+	assert.NoError(t, c.reload(reasonManual))
+	assert.Len(t, c.GetAll(), 2)
+
+	// drift healed by full reload after default interval
+	c.mu.Lock()
+	c.lastFullReload = c.lastFullReload.Add(-defaultFullReloadInterval)
+	c.mu.Unlock()
+	assert.NoError(t, c.reload(reasonManual))
+	assert.Equal(t, int64(2), source.fullLoads.Load())
+	assert.Equal(t, int64(1), source.deltas.Load())
+	assert.Len(t, c.GetAll(), 1)
+	assert.Nil(t, c.Get("key2"))
+}
+
+func testDeltaParams(t *testing.T) {
+	t.Parallel()
+
+	source := newVersionedSource()
+	params := Params[string, int]{
+		Context: context.Background(),
+		Name:    "testing_cache",
+		LoadAllFunc: func(ctx context.Context) (map[string]*int, error) {
+			return nil, nil
+		},
+		LoadChangesFunc: source.loadChanges,
+	}
+	assert.NoError(t, params.check())
+
+	params.LoadAllVersionedFunc = source.loadAll
+	assert.Error(t, params.check())
+
+	params.LoadAllFunc = nil
+	assert.NoError(t, params.check())
+
+	params.Timeouts.FullReloadInterval = -time.Second
+	assert.Error(t, params.check())
+}
@@ -0,0 +1,175 @@
+package codebook
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	cadre_metrics "github.com/moderntv/cadre/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Source is a cache which can be used as a source of derived cache (see `Derive`).
+// It is implemented by `*Cache`.
+type Source interface {
+	addReloadListener(listener func()) (remove func())
+}
+
+// DeriveFunc computes all items of derived cache from its source caches.
+// It should be a pure function reading only source caches.
+type DeriveFunc[K comparable, T any] func() (entries map[K]*T, err error)
+
+type DeriveParams[K comparable, T any] struct {
+	Context         context.Context
+	Log             zerolog.Logger
+	MetricsRegistry *cadre_metrics.Registry
+	// MetricsRegisterer can be used instead of `MetricsRegistry` (see `Params.MetricsRegisterer`).
+	MetricsRegisterer prometheus.Registerer
+	Name              string
+	// Sources are caches whose successful reload triggers recomputation of derived cache.
+	Sources        []Source
+	DeriveFunc     DeriveFunc[K, T]
+	MemsizeEnabled bool
+	Indexes        []Index[K, T]
+	EqualFunc      EqualFunc[T]
+	// CloneOnRead and CloneFunc configure cloning of read items (see `Params.CloneOnRead`).
+	CloneOnRead bool
+	CloneFunc   CloneFunc[T]
+	Validators  []ValidateFunc[K, T]
+	// Retry plans recomputation after failed one (see `Timeouts.Retry`).
+	Retry RetryPolicy
+	// TracerProvider creates span of each recomputation (see `Params.TracerProvider`).
+	TracerProvider trace.TracerProvider
+}
+
+func (p *DeriveParams[K, T]) check() error {
+	if len(p.Sources) == 0 {
+		return errors.New("at least one source must be set")
+	}
+
+	for _, source := range p.Sources {
+		if source == nil {
+			return errors.New("source cannot be nil")
+		}
+	}
+
+	if p.DeriveFunc == nil {
+		return errors.New("DeriveFunc must be provided")
+	}
+
+	return nil
+}
+
+// Derive creates read-only cache whose items are computed by `params.DeriveFunc` from source caches.
+// Items are recomputed asynchronously whenever any of source caches successfully reloads (source reloads
+// during recomputation are coalesced into one following recomputation) and failed recomputation
+// is retried according to `params.Retry`; derived cache has no periodic reloads nor invalidations of its own.
+// Derived cache should be closed before its sources; closing it stops listening to source reloads.
+func Derive[K comparable, T any](params DeriveParams[K, T]) (c *Cache[K, T], err error) {
+	err = params.check()
+	if err != nil {
+		return
+	}
+
+	// listeners are registered before the first computation, so no source reload can be missed
+	var derived atomic.Pointer[Cache[K, T]]
+	var missed atomic.Bool
+	listener := func() {
+		missed.Store(true)
+		// recomputation runs asynchronously, it must not block reload of the source
+		if dc := derived.Load(); dc != nil {
+			dc.invalidate(reasonInvalidation)
+		}
+	}
+
+	removeFuncs := make([]func(), 0, len(params.Sources))
+	for _, source := range params.Sources {
+		removeFuncs = append(removeFuncs, source.addReloadListener(listener))
+	}
+
+	c, err = New(Params[K, T]{
+		Context:           params.Context,
+		Log:               params.Log,
+		MetricsRegistry:   params.MetricsRegistry,
+		MetricsRegisterer: params.MetricsRegisterer,
+		Name:              params.Name,
+		LoadAllFunc: func(_ context.Context) (map[K]*T, error) {
+			return params.DeriveFunc()
+		},
+		MemsizeEnabled: params.MemsizeEnabled,
+		Indexes:        params.Indexes,
+		EqualFunc:      params.EqualFunc,
+		CloneOnRead:    params.CloneOnRead,
+		CloneFunc:      params.CloneFunc,
+		Validators:     params.Validators,
+		Timeouts: Timeouts{
+			Retry: params.Retry,
+		},
+		TracerProvider: params.TracerProvider,
+	})
+	if err != nil {
+		for _, remove := range removeFuncs {
+			remove()
+		}
+		return
+	}
+
+	c.closeFuncs = append(c.closeFuncs, removeFuncs...)
+	derived.Store(c)
+	// source reloaded during the first computation
+	if missed.Load() {
+		c.invalidate(reasonInvalidation)
+	}
+
+	return
+}
+
+// Map creates derived cache with items computed from single `source` cache by `mapFunc`.
+// `params.Sources` and `params.DeriveFunc` are set by this function.
+func Map[K1 comparable, T1 any, K2 comparable, T2 any](
+	source *Cache[K1, T1],
+	mapFunc func(entries map[K1]*T1) (map[K2]*T2, error),
+	params DeriveParams[K2, T2],
+) (*Cache[K2, T2], error) {
+	params.Sources = []Source{source}
+	params.DeriveFunc = func() (map[K2]*T2, error) {
+		return mapFunc(source.GetAll())
+	}
+
+	return Derive(params)
+}
+
+func (c *Cache[K, T]) addReloadListener(listener func()) (remove func()) {
+	c.subscribersMu.Lock()
+	defer c.subscribersMu.Unlock()
+
+	id := c.nextSubscriberID
+	c.nextSubscriberID++
+	if c.reloadListeners == nil {
+		c.reloadListeners = make(map[uint64]func())
+	}
+	c.reloadListeners[id] = listener
+
+	remove = func() {
+		c.subscribersMu.Lock()
+		delete(c.reloadListeners, id)
+		c.subscribersMu.Unlock()
+	}
+
+	return
+}
+
+func (c *Cache[K, T]) notifyReloadListeners() {
+	c.subscribersMu.Lock()
+	listeners := make([]func(), 0, len(c.reloadListeners))
+	for _, listener := range c.reloadListeners {
+		listeners = append(listeners, listener)
+	}
+	c.subscribersMu.Unlock()
+
+	for _, listener := range listeners {
+		listener()
+	}
+}
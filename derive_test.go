@@ -0,0 +1,281 @@
+package codebook
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moderntv/codebook-cache/internal/test_utils"
+)
+
+func TestDerive(t *testing.T) {
+	t.Run("testDeriveJoin", testDeriveJoin)
+	t.Run("testDeriveMap", testDeriveMap)
+	t.Run("testDeriveAsync", testDeriveAsync)
+	t.Run("testDeriveParams", testDeriveParams)
+}
+
+func newTestChannelsCache(t *testing.T, mu *sync.Mutex, channels map[int]*testChannel) *Cache[int, testChannel] {
+	c, err := New(Params[int, testChannel]{
+		Context: context.Background(),
+		Log:     test_utils.Logger(),
+		Name:    "testing_channels",
+		LoadAllFunc: func(ctx context.Context) (map[int]*testChannel, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			copied := make(map[int]*testChannel, len(channels))
+			for key, value := range channels {
+				copied[key] = value
+			}
+			return copied, nil
+		},
+		Timeouts: Timeouts{
+			ReloadInterval: 5 * time.Second,
+		},
+	})
+	assert.NoError(t, err)
+
+	return c
+}
+
+func testDeriveJoin(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	channels := map[int]*testChannel{
+		1: {ID: 1, Package: "basic"},
+		2: {ID: 2, Package: "premium"},
+	}
+	prices := map[string]*int{
+		"basic":   test_utils.IntPointer(10),
+		"premium": test_utils.IntPointer(20),
+	}
+
+	channelsCache := newTestChannelsCache(t, &mu, channels)
+	pricesCache, err := New(Params[string, int]{
+		Context: context.Background(),
+		Log:     test_utils.Logger(),
+		Name:    "testing_prices",
+		LoadAllFunc: func(ctx context.Context) (map[string]*int, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			copied := make(map[string]*int, len(prices))
+			for key, value := range prices {
+				copied[key] = value
+			}
+			return copied, nil
+		},
+		Timeouts: Timeouts{
+			ReloadInterval: 5 * time.Second,
+		},
+	})
+	assert.NoError(t, err)
+
+	// channel price = price of its package
+	channelPrices, err := Derive(DeriveParams[int, int]{
+		Context: context.Background(),
+		Log:     test_utils.Logger(),
+		Name:    "testing_channel_prices",
+		Sources: []Source{channelsCache, pricesCache},
+		DeriveFunc: func() (map[int]*int, error) {
+			prices := pricesCache.GetAll()
+			entries := make(map[int]*int)
+			for id, channel := range channelsCache.GetAll() {
+				if price, exists := prices[channel.Package]; exists {
+					entries[id] = price
+				}
+			}
+			return entries, nil
+		},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, test_utils.IntPointer(10), channelPrices.Get(1))
+	assert.Equal(t, test_utils.IntPointer(20), channelPrices.Get(2))
+
+	mu.Lock()
+	prices["premium"] = test_utils.IntPointer(30)
+	mu.Unlock()
+	assert.NoError(t, pricesCache.reload(reasonManual))
+	assert.Eventually(t, func() bool {
+		return *channelPrices.Get(2) == 30
+	}, time.Second, 5*time.Millisecond)
+
+	mu.Lock()
+	channels[3] = &testChannel{ID: 3, Package: "basic"}
+	mu.Unlock()
+	assert.NoError(t, channelsCache.reload(reasonManual))
+	assert.Eventually(t, func() bool {
+		return channelPrices.Get(3) != nil
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, test_utils.IntPointer(10), channelPrices.Get(3))
+
+	// closed derived cache is not recomputed anymore
+	assert.NoError(t, channelPrices.Close(context.Background()))
+	mu.Lock()
+	channels[4] = &testChannel{ID: 4, Package: "basic"}
+	mu.Unlock()
+	assert.NoError(t, channelsCache.reload(reasonManual))
+	assert.Never(t, func() bool {
+		return channelPrices.Get(4) != nil
+	}, 100*time.Millisecond, 5*time.Millisecond)
+}
+
+func testDeriveMap(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	channels := map[int]*testChannel{
+		1: {ID: 1, Package: "basic"},
+		2: {ID: 2, Package: "premium"},
+		3: {ID: 3, Package: "basic"},
+	}
+	channelsCache := newTestChannelsCache(t, &mu, channels)
+
+	byPackage, err := Map(
+		channelsCache,
+		func(entries map[int]*testChannel) (map[string]*[]int, error) {
+			grouped := make(map[string]*[]int)
+			for id, channel := range entries {
+				ids, exists := grouped[channel.Package]
+				if !exists {
+					ids = &[]int{}
+					grouped[channel.Package] = ids
+				}
+				*ids = append(*ids, id)
+			}
+			return grouped, nil
+		},
+		DeriveParams[string, []int]{
+			Context: context.Background(),
+			Log:     test_utils.Logger(),
+			Name:    "testing_by_package",
+		},
+	)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []int{1, 3}, *byPackage.Get("basic"))
+	assert.ElementsMatch(t, []int{2}, *byPackage.Get("premium"))
+
+	mu.Lock()
+	channels[4] = &testChannel{ID: 4, Package: "premium"}
+	mu.Unlock()
+	channelsCache.InvalidateAll()
+	assert.Eventually(t, func() bool {
+		return len(*byPackage.Get("premium")) == 2
+	}, time.Second, 5*time.Millisecond)
+	assert.ElementsMatch(t, []int{2, 4}, *byPackage.Get("premium"))
+}
+
+func testDeriveAsync(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	channels := map[int]*testChannel{
+		1: {ID: 1, Package: "basic"},
+	}
+	channelsCache := newTestChannelsCache(t, &mu, channels)
+
+	var computations, failures atomic.Int64
+	block := make(chan struct{})
+	counts, err := Map(
+		channelsCache,
+		func(entries map[int]*testChannel) (map[string]*int, error) {
+			if computations.Add(1) > 1 {
+				<-block
+			}
+			if failures.Load() > 0 {
+				failures.Add(-1)
+				return nil, errors.New("cannot derive")
+			}
+			return map[string]*int{
+				"count": test_utils.IntPointer(len(entries)),
+			}, nil
+		},
+		DeriveParams[string, int]{
+			Context: context.Background(),
+			Log:     test_utils.Logger(),
+			Name:    "testing_counts",
+			Retry: RetryPolicy{
+				InitialBackoff: 10 * time.Millisecond,
+			},
+		},
+	)
+	assert.NoError(t, err)
+	defer counts.Close(context.Background())
+
+	// source reloads are not blocked by running recomputation
+	for i := 2; i <= 4; i++ {
+		mu.Lock()
+		channels[i] = &testChannel{ID: i, Package: "basic"}
+		mu.Unlock()
+
+		done := make(chan error)
+		go func() {
+			done <- channelsCache.reload(reasonManual)
+		}()
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("source reload blocked by derived cache")
+		}
+	}
+	close(block)
+	assert.Eventually(t, func() bool {
+		return *counts.Get("count") == 4
+	}, time.Second, 5*time.Millisecond)
+
+	// failed recomputation is retried
+	failures.Store(2)
+	mu.Lock()
+	channels[5] = &testChannel{ID: 5, Package: "basic"}
+	mu.Unlock()
+	assert.NoError(t, channelsCache.reload(reasonManual))
+	assert.Eventually(t, func() bool {
+		return *counts.Get("count") == 5
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int64(0), failures.Load())
+}
+
+func testDeriveParams(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	channelsCache := newTestChannelsCache(t, &mu, map[int]*testChannel{})
+
+	_, err := Derive(DeriveParams[int, int]{
+		Context: context.Background(),
+		Name:    "testing_derived",
+		DeriveFunc: func() (map[int]*int, error) {
+			return nil, nil
+		},
+	})
+	assert.Error(t, err)
+
+	_, err = Derive(DeriveParams[int, int]{
+		Context: context.Background(),
+		Name:    "testing_derived",
+		Sources: []Source{channelsCache},
+	})
+	assert.Error(t, err)
+
+	_, err = Derive(DeriveParams[int, int]{
+		Context: context.Background(),
+		Name:    "testing_derived",
+		Sources: []Source{channelsCache},
+		DeriveFunc: func() (map[int]*int, error) {
+			return nil, errors.New("cannot derive")
+		},
+	})
+	assert.Error(t, err)
+
+	// failed derived cache does not listen to source reloads
+	assert.Empty(t, channelsCache.reloadListeners)
+}
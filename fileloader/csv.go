@@ -0,0 +1,118 @@
+package fileloader
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// decodeCSV decodes rows into structs, columns are mapped to fields by header row
+func decodeCSV[T any](data []byte) (items []*T, err error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.TrimLeadingSpace = true
+
+	rows, err := reader.ReadAll()
+	if err != nil || len(rows) == 0 {
+		return
+	}
+
+	itemType := reflect.TypeOf((*T)(nil)).Elem()
+	if itemType.Kind() != reflect.Struct {
+		return nil, errors.New("CSV can be decoded only into struct")
+	}
+
+	fields, err := csvFields(itemType, rows[0])
+	if err != nil {
+		return
+	}
+
+	items = make([]*T, 0, len(rows)-1)
+	for i, row := range rows[1:] {
+		item := new(T)
+		value := reflect.ValueOf(item).Elem()
+		for column, field := range fields {
+			err = setField(value.FieldByIndex(field), row[column])
+			if err != nil {
+				return nil, fmt.Errorf("row %d, column %s: %w", i+2, rows[0][column], err)
+			}
+		}
+		items = append(items, item)
+	}
+
+	return
+}
+
+// csvFields returns index of field for each column from `header`
+func csvFields(itemType reflect.Type, header []string) (fields [][]int, err error) {
+	byName := make(map[string][]int, itemType.NumField())
+	for _, field := range reflect.VisibleFields(itemType) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+
+		name := strings.ToLower(field.Name)
+		tag, ok := field.Tag.Lookup("csv")
+		if ok {
+			name, _, _ = strings.Cut(tag, ",")
+			if name == "-" {
+				continue
+			}
+		}
+		byName[name] = field.Index
+	}
+
+	fields = make([][]int, len(header))
+	for i, column := range header {
+		index, ok := byName[column]
+		if !ok {
+			index, ok = byName[strings.ToLower(column)]
+		}
+		if !ok {
+			return nil, fmt.Errorf("no field for column %s", column)
+		}
+		fields[i] = index
+	}
+
+	return
+}
+
+// setField sets `value` parsed according to type of `field` (empty value keeps zero value)
+func setField(field reflect.Value, value string) (err error) {
+	if value == "" {
+		return nil
+	}
+
+	if field.Kind() == reflect.Pointer {
+		field.Set(reflect.New(field.Type().Elem()))
+		field = field.Elem()
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		var b bool
+		b, err = strconv.ParseBool(value)
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		i, err = strconv.ParseInt(value, 10, field.Type().Bits())
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var u uint64
+		u, err = strconv.ParseUint(value, 10, field.Type().Bits())
+		field.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		f, err = strconv.ParseFloat(value, field.Type().Bits())
+		field.SetFloat(f)
+	default:
+		err = fmt.Errorf("unsupported field type %s", field.Type())
+	}
+
+	return
+}
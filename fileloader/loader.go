@@ -0,0 +1,215 @@
+// Package fileloader loads codebook items from JSON, YAML or CSV files (e.g. mounted from Kubernetes
+// ConfigMaps) and invalidates caches when the files change.
+package fileloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	codebook "github.com/moderntv/codebook-cache"
+	"github.com/moderntv/codebook-cache/internal/decode"
+	"github.com/moderntv/codebook-cache/internal/utils"
+)
+
+// Format of loaded files.
+type Format string
+
+const (
+	// FormatAuto detects format of each file from its extension.
+	FormatAuto Format = ""
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+	FormatCSV  Format = "csv"
+)
+
+type Params[K comparable, T any] struct {
+	// Path to file or directory. All files with supported extension (`.json`, `.yaml`, `.yml`, `.csv`)
+	// are loaded from directory; hidden files (e.g. `..data` used by Kubernetes volumes)
+	// and subdirectories are skipped.
+	Path string
+	// Format of files (detected from file extension when not set).
+	Format Format
+	// KeyFunc extracts key of each loaded item.
+	KeyFunc codebook.KeyFunc[K, T]
+}
+
+func (p *Params[K, T]) check() error {
+	if p.Path == "" {
+		return errors.New("Path must be set")
+	}
+
+	if p.KeyFunc == nil {
+		return errors.New("KeyFunc must be set")
+	}
+
+	switch p.Format {
+	case FormatAuto, FormatJSON, FormatYAML, FormatCSV:
+	default:
+		return fmt.Errorf("unknown format %q", p.Format)
+	}
+
+	return nil
+}
+
+// New creates function loading all items from files. JSON and YAML files contain either a list
+// of items or an object (map) of items (its keys are ignored, items are keyed by `KeyFunc`).
+// CSV files contain header row with column names mapped to fields of struct `T` by `csv` tags
+// (or by case-insensitive field names). Duplicate keys are reported as error.
+func New[K comparable, T any](params Params[K, T]) (loadAllFunc codebook.LoadAllFunc[K, T], err error) {
+	err = params.check()
+	if err != nil {
+		return
+	}
+
+	loadAllFunc = func(ctx context.Context) (entries map[K]*T, err error) {
+		files, err := listFiles(params.Path, params.Format)
+		if err != nil {
+			return
+		}
+
+		entries = make(map[K]*T)
+		for _, file := range files {
+			err = ctx.Err()
+			if err != nil {
+				return nil, err
+			}
+
+			err = loadFile(file, params, entries)
+			if err != nil {
+				return nil, fmt.Errorf("cannot load %s: %w", file.path, err)
+			}
+		}
+
+		return
+	}
+
+	return
+}
+
+type file struct {
+	path   string
+	format Format
+}
+
+// listFiles returns files which should be loaded from `path` sorted by name
+func listFiles(path string, format Format) (files []file, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+
+	if !info.IsDir() {
+		if format == FormatAuto {
+			format = formatOf(path)
+			if format == FormatAuto {
+				return nil, fmt.Errorf("cannot detect format of %s", path)
+			}
+		}
+		return []file{{path: path, format: format}}, nil
+	}
+
+	dirEntries, err := os.ReadDir(path)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range dirEntries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		filePath := filepath.Join(path, name)
+		// entries of Kubernetes volumes are symlinks
+		entryInfo, err := os.Stat(filePath)
+		if err != nil || entryInfo.IsDir() {
+			continue
+		}
+
+		fileFormat := formatOf(name)
+		if fileFormat == FormatAuto || (format != FormatAuto && fileFormat != format) {
+			continue
+		}
+
+		files = append(files, file{path: filePath, format: fileFormat})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].path < files[j].path
+	})
+
+	return
+}
+
+func formatOf(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".csv":
+		return FormatCSV
+	default:
+		return FormatAuto
+	}
+}
+
+// loadFile decodes items from file and adds them into `entries`
+func loadFile[K comparable, T any](f file, params Params[K, T], entries map[K]*T) (err error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return
+	}
+
+	var items []*T
+	switch f.format {
+	case FormatJSON:
+		items, err = decode.JSON[T](data)
+	case FormatYAML:
+		items, err = decodeYAML[T](data)
+	case FormatCSV:
+		items, err = decodeCSV[T](data)
+	}
+	if err != nil {
+		return
+	}
+
+	for _, item := range items {
+		err = utils.AddEntry(entries, params.KeyFunc(item), item)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func decodeYAML[T any](data []byte) (items []*T, err error) {
+	var node yaml.Node
+	err = yaml.Unmarshal(data, &node)
+	if err != nil || len(node.Content) == 0 {
+		return
+	}
+
+	if node.Content[0].Kind == yaml.MappingNode {
+		var object map[string]*T
+		err = node.Decode(&object)
+		if err != nil {
+			return
+		}
+		return decode.ObjectItems(object)
+	}
+
+	err = node.Decode(&items)
+	if err != nil {
+		return
+	}
+	return items, decode.CheckItems(items)
+}
@@ -0,0 +1,170 @@
+package fileloader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type country struct {
+	Code       string  `json:"code" yaml:"code" csv:"iso_code"`
+	Name       string  `json:"name" yaml:"name"`
+	Population int64   `json:"population" yaml:"population"`
+	Area       float64 `json:"area" yaml:"area"`
+	EU         *bool   `json:"eu" yaml:"eu" csv:"eu"`
+}
+
+func countryCode(c *country) string {
+	return c.Code
+}
+
+func boolPointer(b bool) *bool {
+	return &b
+}
+
+func writeFile(t *testing.T, path string, data string) {
+	assert.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+}
+
+func TestLoader(t *testing.T) {
+	t.Run("testLoaderFormats", testLoaderFormats)
+	t.Run("testLoaderDirectory", testLoaderDirectory)
+	t.Run("testLoaderErrors", testLoaderErrors)
+}
+
+func testLoaderFormats(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	expected := map[string]*country{
+		"CZ": {Code: "CZ", Name: "Czechia", Population: 10900000, Area: 78871, EU: boolPointer(true)},
+		"NO": {Code: "NO", Name: "Norway", Population: 5500000, Area: 385207, EU: boolPointer(false)},
+	}
+
+	files := map[string]string{
+		"list.json": `[
+			{"code": "CZ", "name": "Czechia", "population": 10900000, "area": 78871, "eu": true},
+			{"code": "NO", "name": "Norway", "population": 5500000, "area": 385207, "eu": false}
+		]`,
+		"object.json": `{
+			"cz": {"code": "CZ", "name": "Czechia", "population": 10900000, "area": 78871, "eu": true},
+			"no": {"code": "NO", "name": "Norway", "population": 5500000, "area": 385207, "eu": false}
+		}`,
+		"list.yaml": `
+- code: CZ
+  name: Czechia
+  population: 10900000
+  area: 78871
+  eu: true
+- code: "NO"
+  name: Norway
+  population: 5500000
+  area: 385207
+  eu: false
+`,
+		"object.yml": `
+cz: {code: CZ, name: Czechia, population: 10900000, area: 78871, eu: true}
+no: {code: "NO", name: Norway, population: 5500000, area: 385207, eu: false}
+`,
+		"countries.csv": "iso_code,Name,population,area,eu\nCZ,Czechia,10900000,78871,true\nNO,Norway,5500000,385207,false\n",
+	}
+
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		writeFile(t, path, data)
+
+		loadAllFunc, err := New(Params[string, country]{
+			Path:    path,
+			KeyFunc: countryCode,
+		})
+		assert.NoError(t, err)
+
+		entries, err := loadAllFunc(context.Background())
+		assert.NoError(t, err, name)
+		assert.Equal(t, expected, entries, name)
+	}
+
+	// format is not detected from extension
+	path := filepath.Join(dir, "countries.txt")
+	writeFile(t, path, files["list.json"])
+	loadAllFunc, err := New(Params[string, country]{Path: path, KeyFunc: countryCode})
+	assert.NoError(t, err)
+	_, err = loadAllFunc(context.Background())
+	assert.Error(t, err)
+
+	loadAllFunc, err = New(Params[string, country]{Path: path, Format: FormatJSON, KeyFunc: countryCode})
+	assert.NoError(t, err)
+	entries, err := loadAllFunc(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, expected, entries)
+}
+
+func testLoaderDirectory(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.json"), `[{"code": "CZ"}]`)
+	writeFile(t, filepath.Join(dir, "b.yaml"), `[{code: SK}]`)
+	writeFile(t, filepath.Join(dir, "c.csv"), "iso_code,population\nPL,\n")
+	writeFile(t, filepath.Join(dir, "readme.md"), "not loaded")
+	writeFile(t, filepath.Join(dir, ".hidden.json"), `[{"code": "XX"}]`)
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "subdir"), 0o755))
+	writeFile(t, filepath.Join(dir, "subdir", "d.json"), `[{"code": "YY"}]`)
+
+	loadAllFunc, err := New(Params[string, country]{Path: dir, KeyFunc: countryCode})
+	assert.NoError(t, err)
+	entries, err := loadAllFunc(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]*country{
+		"CZ": {Code: "CZ"},
+		"SK": {Code: "SK"},
+		"PL": {Code: "PL"},
+	}, entries)
+
+	// only files of given format
+	loadAllFunc, err = New(Params[string, country]{Path: dir, Format: FormatYAML, KeyFunc: countryCode})
+	assert.NoError(t, err)
+	entries, err = loadAllFunc(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]*country{"SK": {Code: "SK"}}, entries)
+}
+
+func testLoaderErrors(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	_, err := New(Params[string, country]{Path: dir})
+	assert.Error(t, err)
+	_, err = New(Params[string, country]{KeyFunc: countryCode})
+	assert.Error(t, err)
+	_, err = New(Params[string, country]{Path: dir, Format: "xml", KeyFunc: countryCode})
+	assert.Error(t, err)
+
+	cases := map[string]string{
+		"duplicate.json": `[{"code": "CZ"}, {"code": "CZ"}]`,
+		"invalid.json":   `[{"code": `,
+		"null.json":      `[null]`,
+		"null.yaml":      "a: null\n",
+		"nulls.yaml":     "- code: CZ\n- null\n",
+		"invalid.csv":    "iso_code,population\nCZ,many\n",
+		"unknown.csv":    "iso_code,capital\nCZ,Prague\n",
+	}
+	for name, data := range cases {
+		path := filepath.Join(dir, name)
+		writeFile(t, path, data)
+
+		loadAllFunc, err := New(Params[string, country]{Path: path, KeyFunc: countryCode})
+		assert.NoError(t, err)
+		_, err = loadAllFunc(context.Background())
+		assert.Error(t, err, name)
+	}
+
+	loadAllFunc, err := New(Params[string, country]{Path: filepath.Join(dir, "missing.json"), KeyFunc: countryCode})
+	assert.NoError(t, err)
+	_, err = loadAllFunc(context.Background())
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,154 @@
+package fileloader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog"
+
+	codebook "github.com/moderntv/codebook-cache"
+)
+
+// MetadataPath is metadata key of watched path in emitted invalidation events.
+const MetadataPath = "codebook.path"
+
+// WatchSource is invalidation source which invalidates all items when loaded files change.
+// Directory containing the path is watched, so files replaced by rename or by atomic swap
+// of symlinks (used by Kubernetes volume updates) are detected too.
+type WatchSource[K comparable] struct {
+	log    zerolog.Logger
+	path   string
+	format Format
+	// state of files when source was created (before cache loads them)
+	baseline string
+	// attributes protected by mutex
+	mu      sync.Mutex
+	watcher *fsnotify.Watcher
+}
+
+// NewWatchSource creates invalidation source watching files loaded from `params.Path`
+// (see `Params`). Source must be created before the cache, files changed after its creation
+// and before the cache starts watching (after the initial load) are invalidated on start.
+func NewWatchSource[K comparable, T any](log zerolog.Logger, params Params[K, T]) *WatchSource[K] {
+	s := &WatchSource[K]{
+		log:    log.With().Str("path", params.Path).Logger(),
+		path:   params.Path,
+		format: params.Format,
+	}
+	s.baseline = s.state()
+
+	return s
+}
+
+func (s *WatchSource[K]) Name() string {
+	return "file_watch"
+}
+
+func (s *WatchSource[K]) Start(ctx context.Context, emit func(codebook.InvalidationEvent[K])) (err error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return
+	}
+
+	dir := s.path
+	info, err := os.Stat(s.path)
+	if err != nil {
+		_ = watcher.Close()
+		return
+	}
+	if !info.IsDir() {
+		dir = filepath.Dir(s.path)
+	}
+
+	err = watcher.Add(dir)
+	if err != nil {
+		_ = watcher.Close()
+		return fmt.Errorf("cannot watch %s: %w", dir, err)
+	}
+
+	s.mu.Lock()
+	s.watcher = watcher
+	s.mu.Unlock()
+
+	state := s.state()
+	if state != s.baseline {
+		s.log.Debug().Msg("files changed before watching started")
+		emit(codebook.InvalidationEvent[K]{
+			Metadata: map[string]string{MetadataPath: s.path},
+		})
+	}
+
+	go func() {
+		for {
+			select {
+			case _, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				// many events are received for one change (or for changes of other files in directory)
+				current := s.state()
+				if current == state {
+					continue
+				}
+				state = current
+
+				s.log.Debug().Msg("files changed")
+				emit(codebook.InvalidationEvent[K]{
+					Metadata: map[string]string{MetadataPath: s.path},
+				})
+
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				s.log.Warn().Err(err).Msg("watching files failed")
+
+			case <-ctx.Done():
+				s.Stop()
+				return
+			}
+		}
+	}()
+
+	return
+}
+
+func (s *WatchSource[K]) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.watcher != nil {
+		_ = s.watcher.Close() // ignore error - watcher is already closed
+		s.watcher = nil
+	}
+}
+
+// state describes loaded files (resolved paths, sizes and modification times)
+func (s *WatchSource[K]) state() string {
+	files, err := listFiles(s.path, s.format)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+
+	state := ""
+	for _, f := range files {
+		resolved, err := filepath.EvalSymlinks(f.path)
+		if err != nil {
+			resolved = f.path
+		}
+
+		info, err := os.Stat(resolved)
+		if err != nil {
+			state += fmt.Sprintf("%s:error;", f.path)
+			continue
+		}
+		state += fmt.Sprintf("%s:%s:%d:%d;", f.path, resolved, info.Size(), info.ModTime().UnixNano())
+	}
+
+	return state
+}
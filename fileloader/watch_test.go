@@ -0,0 +1,142 @@
+package fileloader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	codebook "github.com/moderntv/codebook-cache"
+	"github.com/moderntv/codebook-cache/internal/test_utils"
+)
+
+func TestWatch(t *testing.T) {
+	t.Run("testWatchFile", testWatchFile)
+	t.Run("testWatchSymlinkSwap", testWatchSymlinkSwap)
+	t.Run("testWatchBaseline", testWatchBaseline)
+}
+
+func newWatchedCache(t *testing.T, params Params[string, country]) *codebook.Cache[string, country] {
+	loadAllFunc, err := New(params)
+	assert.NoError(t, err)
+
+	return newWatchedCacheWithLoader(t, params, loadAllFunc)
+}
+
+func newWatchedCacheWithLoader(
+	t *testing.T,
+	params Params[string, country],
+	loadAllFunc codebook.LoadAllFunc[string, country],
+) *codebook.Cache[string, country] {
+	source := NewWatchSource(test_utils.Logger(), params)
+	c, err := codebook.New(codebook.Params[string, country]{
+		Context:     context.Background(),
+		Log:         test_utils.Logger(),
+		Name:        "testing_cache",
+		LoadAllFunc: loadAllFunc,
+		InvalidationSources: []codebook.InvalidationSource[string]{
+			source,
+		},
+		Timeouts: codebook.Timeouts{
+			ReloadInterval: 10 * time.Second,
+			ReloadDelay:    50 * time.Millisecond,
+		},
+	})
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		_ = c.Close(context.Background())
+	})
+
+	return c
+}
+
+func testWatchFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "countries.json")
+	writeFile(t, path, `[{"code": "CZ", "name": "Czechia"}]`)
+
+	c := newWatchedCache(t, Params[string, country]{Path: path, KeyFunc: countryCode})
+	assert.Equal(t, "Czechia", c.Get("CZ").Name)
+
+	// file rewritten in place
+	writeFile(t, path, `[{"code": "CZ", "name": "Czech Republic"}]`)
+	assert.Eventually(t, func() bool {
+		return c.Get("CZ").Name == "Czech Republic"
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// file replaced by rename
+	tmpPath := filepath.Join(dir, "countries.json.tmp")
+	writeFile(t, tmpPath, `[{"code": "CZ", "name": "Czechia"}, {"code": "SK", "name": "Slovakia"}]`)
+	assert.NoError(t, os.Rename(tmpPath, path))
+	assert.Eventually(t, func() bool {
+		return c.Get("SK") != nil
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "Czechia", c.Get("CZ").Name)
+	assert.Equal(t, "Slovakia", c.Get("SK").Name)
+
+	// other files in directory are ignored
+	status := c.Status()
+	writeFile(t, filepath.Join(dir, "other.json"), `[]`)
+	assert.Never(t, func() bool {
+		return !c.Status().LastSuccess.Equal(status.LastSuccess)
+	}, 300*time.Millisecond, 10*time.Millisecond)
+}
+
+// testWatchSymlinkSwap simulates update of Kubernetes ConfigMap volume
+func testWatchSymlinkSwap(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeVersion := func(version string, data string) {
+		versionDir := filepath.Join(dir, "..version_"+version)
+		assert.NoError(t, os.Mkdir(versionDir, 0o755))
+		writeFile(t, filepath.Join(versionDir, "countries.yaml"), data)
+
+		// atomic swap of ..data symlink
+		assert.NoError(t, os.Symlink("..version_"+version, filepath.Join(dir, "..data_tmp")))
+		assert.NoError(t, os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+	}
+
+	writeVersion("1", `[{code: CZ, name: Czechia}]`)
+	assert.NoError(t, os.Symlink(filepath.Join("..data", "countries.yaml"), filepath.Join(dir, "countries.yaml")))
+
+	c := newWatchedCache(t, Params[string, country]{Path: dir, KeyFunc: countryCode})
+	assert.Equal(t, "Czechia", c.Get("CZ").Name)
+
+	writeVersion("2", `[{code: CZ, name: Czech Republic}]`)
+	assert.Eventually(t, func() bool {
+		return c.Get("CZ").Name == "Czech Republic"
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func testWatchBaseline(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "countries.json")
+	writeFile(t, path, `[{"code": "CZ", "name": "Czechia"}]`)
+
+	params := Params[string, country]{Path: path, KeyFunc: countryCode}
+	loadAllFunc, err := New(params)
+	assert.NoError(t, err)
+
+	// file changes after the initial load, before watching starts
+	loads := 0
+	c := newWatchedCacheWithLoader(t, params, func(ctx context.Context) (map[string]*country, error) {
+		entries, err := loadAllFunc(ctx)
+		loads++
+		if loads == 1 {
+			writeFile(t, path, `[{"code": "CZ", "name": "Czech Republic"}]`)
+		}
+		return entries, err
+	})
+
+	assert.Eventually(t, func() bool {
+		return c.Get("CZ").Name == "Czech Republic"
+	}, 2*time.Second, 10*time.Millisecond)
+}
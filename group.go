@@ -0,0 +1,307 @@
+package codebook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	cadre_metrics "github.com/moderntv/cadre/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+)
+
+// GroupParams configures `Group`. `MetricsRegistry` (or `MetricsRegisterer`) and `Timeouts` are used
+// as defaults for registered caches which do not set their own.
+type GroupParams struct {
+	Log               zerolog.Logger
+	MetricsRegistry   *cadre_metrics.Registry
+	MetricsRegisterer prometheus.Registerer
+	Timeouts          Timeouts
+	// StartTimeout limits duration of all initial loads performed by `Group.Start` (0 means no limit).
+	StartTimeout time.Duration
+	// Concurrency limits number of initial loads running at once (0 means no limit).
+	Concurrency int
+}
+
+func (p *GroupParams) check() error {
+	if p.MetricsRegistry != nil && p.MetricsRegisterer != nil {
+		return errors.New("only one of MetricsRegistry and MetricsRegisterer can be provided")
+	}
+
+	if p.StartTimeout < 0 {
+		return errors.New("StartTimeout cannot be negative")
+	}
+
+	if p.Concurrency < 0 {
+		return errors.New("Concurrency cannot be negative")
+	}
+
+	return p.Timeouts.check()
+}
+
+// Group creates many caches at once. Caches are registered by `Register`, their initial loads
+// are performed in parallel by `Start` and all of them are closed together by `Close`.
+type Group struct {
+	log    zerolog.Logger
+	params GroupParams
+	// attributes protected by mutex
+	mu      sync.Mutex
+	members []groupMember
+	started bool
+}
+
+type groupMember interface {
+	name() string
+	start(ctx context.Context) error
+	close(ctx context.Context) error
+	status() MemberStatus
+	healthCheck() error
+}
+
+// MemberStatus describes state of one cache registered in group.
+type MemberStatus struct {
+	Name  string
+	Ready bool
+	// Err is the initial load error (nil when not started yet or loaded successfully).
+	Err error
+}
+
+func NewGroup(params GroupParams) (g *Group, err error) {
+	err = params.check()
+	if err != nil {
+		return
+	}
+
+	g = &Group{
+		log:    params.Log,
+		params: params,
+	}
+
+	return
+}
+
+// GroupMember is a handle of cache registered in group. The cache is available after successful
+// `Group.Start`.
+type GroupMember[K comparable, T any] struct {
+	params Params[K, T]
+	// attributes protected by mutex
+	mu    sync.Mutex
+	cache *Cache[K, T]
+	err   error
+}
+
+// Register adds cache created from `params` into group. Params are checked immediately,
+// data are loaded by `Group.Start`. Caches cannot be registered after the group was started.
+func Register[K comparable, T any](g *Group, params Params[K, T]) (m *GroupMember[K, T], err error) {
+	if params.MetricsRegistry == nil && params.MetricsRegisterer == nil {
+		params.MetricsRegistry = g.params.MetricsRegistry
+		params.MetricsRegisterer = g.params.MetricsRegisterer
+	}
+	if params.Timeouts == (Timeouts{}) {
+		params.Timeouts = g.params.Timeouts
+	}
+
+	err = params.check()
+	if err != nil {
+		err = fmt.Errorf("cache %s: %w", params.Name, err)
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.started {
+		err = errors.New("group already started")
+		return
+	}
+
+	for _, member := range g.members {
+		if member.name() == params.Name {
+			err = fmt.Errorf("cache %s already registered", params.Name)
+			return
+		}
+	}
+
+	m = &GroupMember[K, T]{
+		params: params,
+	}
+	g.members = append(g.members, m)
+
+	return
+}
+
+// Cache returns created cache or `nil` when group has not been started yet or the initial load failed.
+func (m *GroupMember[K, T]) Cache() *Cache[K, T] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.cache
+}
+
+func (m *GroupMember[K, T]) name() string {
+	return m.params.Name
+}
+
+func (m *GroupMember[K, T]) start(ctx context.Context) (err error) {
+	c, err := newCache(m.params)
+	if err == nil {
+		err = c.start(ctx)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err != nil {
+		m.err = err
+		return
+	}
+
+	m.cache = c
+	return
+}
+
+func (m *GroupMember[K, T]) close(ctx context.Context) error {
+	c := m.Cache()
+	if c == nil {
+		return nil
+	}
+
+	return c.Close(ctx)
+}
+
+func (m *GroupMember[K, T]) status() MemberStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return MemberStatus{
+		Name:  m.params.Name,
+		Ready: m.cache != nil,
+		Err:   m.err,
+	}
+}
+
+func (m *GroupMember[K, T]) healthCheck() error {
+	m.mu.Lock()
+	c, err := m.cache, m.err
+	m.mu.Unlock()
+
+	if c == nil {
+		if err == nil {
+			err = errors.New("not loaded")
+		}
+		return err
+	}
+
+	return c.HealthCheck()
+}
+
+// Start performs initial loads of all registered caches in parallel (at most `Concurrency` at once)
+// and waits until all of them finish or `StartTimeout` passes. Returns joined errors of all caches
+// which failed to load; successfully loaded caches are usable even when error is returned.
+// Group can be started only once.
+func (g *Group) Start(ctx context.Context) (err error) {
+	g.mu.Lock()
+	if g.started {
+		g.mu.Unlock()
+		return errors.New("group already started")
+	}
+	g.started = true
+	members := g.members
+	g.mu.Unlock()
+
+	if g.params.StartTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.params.StartTimeout)
+		defer cancel()
+	}
+
+	concurrency := g.params.Concurrency
+	if concurrency == 0 {
+		concurrency = len(members)
+	}
+	semaphore := make(chan struct{}, concurrency)
+
+	start := time.Now()
+	errs := make([]error, len(members))
+	wg := sync.WaitGroup{}
+	for i, member := range members {
+		wg.Add(1)
+		go func(i int, member groupMember) {
+			defer wg.Done()
+
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
+				errs[i] = fmt.Errorf("cache %s: %w", member.name(), ctx.Err())
+				return
+			}
+			defer func() { <-semaphore }()
+
+			err := member.start(ctx)
+			if err != nil {
+				errs[i] = fmt.Errorf("cache %s: %w", member.name(), err)
+			}
+		}(i, member)
+	}
+	wg.Wait()
+
+	err = errors.Join(errs...)
+	logEvent := g.log.Info()
+	if err != nil {
+		logEvent = g.log.Warn().Err(err)
+	}
+	logEvent.
+		Int("count", len(members)).
+		Float64("duration_s", time.Since(start).Round(time.Millisecond).Seconds()).
+		Msg("cache group started")
+
+	return
+}
+
+// Ready returns true when group has been started and all caches loaded successfully.
+func (g *Group) Ready() bool {
+	for _, status := range g.Status() {
+		if !status.Ready {
+			return false
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.started
+}
+
+// Status returns statuses of all registered caches in order of registration.
+func (g *Group) Status() (statuses []MemberStatus) {
+	g.mu.Lock()
+	members := g.members
+	g.mu.Unlock()
+
+	statuses = make([]MemberStatus, 0, len(members))
+	for _, member := range members {
+		statuses = append(statuses, member.status())
+	}
+
+	return
+}
+
+// Close closes all caches in reverse order of registration. Returns joined errors of all caches
+// which were not closed properly.
+func (g *Group) Close(ctx context.Context) error {
+	g.mu.Lock()
+	members := g.members
+	g.mu.Unlock()
+
+	errs := make([]error, 0)
+	for i := len(members) - 1; i >= 0; i-- {
+		err := members[i].close(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cache %s: %w", members[i].name(), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
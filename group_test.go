@@ -0,0 +1,174 @@
+package codebook
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moderntv/codebook-cache/internal/test_utils"
+)
+
+func TestGroup(t *testing.T) {
+	t.Run("testGroupStart", testGroupStart)
+	t.Run("testGroupStartTimeout", testGroupStartTimeout)
+	t.Run("testGroupRegister", testGroupRegister)
+}
+
+func slowIntsParams(name string, delay time.Duration, running, maxRunning *atomic.Int64) Params[string, int] {
+	return Params[string, int]{
+		Context: context.Background(),
+		Log:     test_utils.Logger(),
+		Name:    name,
+		LoadAllFunc: func(ctx context.Context) (map[string]*int, error) {
+			current := running.Add(1)
+			defer running.Add(-1)
+			for {
+				max := maxRunning.Load()
+				if current <= max || maxRunning.CompareAndSwap(max, current) {
+					break
+				}
+			}
+
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+
+			return map[string]*int{
+				name: test_utils.IntPointer(1),
+			}, nil
+		},
+	}
+}
+
+func testGroupStart(t *testing.T) {
+	t.Parallel()
+
+	var running, maxRunning atomic.Int64
+	registry := test_utils.Metrics("testing_group")
+
+	g, err := NewGroup(GroupParams{
+		Log:             test_utils.Logger(),
+		MetricsRegistry: registry,
+		Timeouts: Timeouts{
+			ReloadInterval: 5 * time.Second,
+		},
+		Concurrency: 2,
+	})
+	assert.NoError(t, err)
+
+	members := make([]*GroupMember[string, int], 0)
+	for _, name := range []string{"cache_a", "cache_b", "cache_c", "cache_d"} {
+		m, err := Register(g, slowIntsParams(name, 200*time.Millisecond, &running, &maxRunning))
+		assert.NoError(t, err)
+		assert.Nil(t, m.Cache())
+		members = append(members, m)
+	}
+	assert.False(t, g.Ready())
+
+	start := time.Now()
+	assert.NoError(t, g.Start(context.Background()))
+	assert.Less(t, time.Since(start), 700*time.Millisecond)
+	assert.Equal(t, int64(2), maxRunning.Load())
+	assert.True(t, g.Ready())
+
+	for _, m := range members {
+		c := m.Cache()
+		assert.NotNil(t, c)
+		assert.Equal(t, test_utils.IntPointer(1), c.Get(m.name()))
+		// group defaults are used
+		assert.Equal(t, 5*time.Second, c.timeouts.ReloadInterval)
+		assert.NotNil(t, c.metrics)
+	}
+
+	// group can be started only once
+	assert.Error(t, g.Start(context.Background()))
+
+	assert.NoError(t, g.Close(context.Background()))
+	_, err = registry.Get("cache_cache_a_items_count")
+	assert.Error(t, err)
+}
+
+func testGroupStartTimeout(t *testing.T) {
+	t.Parallel()
+
+	var running, maxRunning atomic.Int64
+
+	g, err := NewGroup(GroupParams{
+		Log:          test_utils.Logger(),
+		StartTimeout: 300 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+
+	fast, err := Register(g, slowIntsParams("fast", 10*time.Millisecond, &running, &maxRunning))
+	assert.NoError(t, err)
+	slow, err := Register(g, slowIntsParams("slow", 5*time.Second, &running, &maxRunning))
+	assert.NoError(t, err)
+
+	start := time.Now()
+	err = g.Start(context.Background())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+
+	assert.NotNil(t, fast.Cache())
+	assert.Nil(t, slow.Cache())
+	assert.False(t, g.Ready())
+
+	statuses := g.Status()
+	assert.Len(t, statuses, 2)
+	assert.Equal(t, "fast", statuses[0].Name)
+	assert.True(t, statuses[0].Ready)
+	assert.NoError(t, statuses[0].Err)
+	assert.Equal(t, "slow", statuses[1].Name)
+	assert.False(t, statuses[1].Ready)
+	assert.Error(t, statuses[1].Err)
+
+	assert.NoError(t, g.Close(context.Background()))
+}
+
+func testGroupRegister(t *testing.T) {
+	t.Parallel()
+
+	var running, maxRunning atomic.Int64
+
+	_, err := NewGroup(GroupParams{Concurrency: -1})
+	assert.Error(t, err)
+
+	g, err := NewGroup(GroupParams{})
+	assert.NoError(t, err)
+
+	_, err = Register(g, slowIntsParams("cache_a", 0, &running, &maxRunning))
+	assert.NoError(t, err)
+
+	// duplicate name
+	_, err = Register(g, slowIntsParams("cache_a", 0, &running, &maxRunning))
+	assert.Error(t, err)
+
+	// invalid params
+	_, err = Register(g, Params[string, int]{
+		Context: context.Background(),
+		Name:    "cache_b",
+	})
+	assert.Error(t, err)
+
+	// failing cache
+	_, err = Register(g, Params[string, int]{
+		Context: context.Background(),
+		Name:    "cache_c",
+		LoadAllFunc: func(ctx context.Context) (map[string]*int, error) {
+			return nil, errors.New("cannot load")
+		},
+	})
+	assert.NoError(t, err)
+
+	assert.Error(t, g.Start(context.Background()))
+
+	// cannot register after start
+	_, err = Register(g, slowIntsParams("cache_d", 0, &running, &maxRunning))
+	assert.Error(t, err)
+}
@@ -0,0 +1,138 @@
+// Package httploader loads codebook items from HTTP endpoints returning JSON.
+package httploader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	codebook "github.com/moderntv/codebook-cache"
+	"github.com/moderntv/codebook-cache/internal/decode"
+	"github.com/moderntv/codebook-cache/internal/utils"
+)
+
+type Params[K comparable, T any] struct {
+	// Client performing requests (`http.DefaultClient` when not set).
+	Client *http.Client
+	// URL of the endpoint.
+	URL string
+	// Header contains additional request headers (e.g. `Authorization`).
+	Header http.Header
+	// KeyFunc extracts key of each loaded item.
+	KeyFunc codebook.KeyFunc[K, T]
+}
+
+func (p *Params[K, T]) check() error {
+	if p.URL == "" {
+		return errors.New("URL must be set")
+	}
+
+	if p.KeyFunc == nil {
+		return errors.New("KeyFunc must be set")
+	}
+
+	return nil
+}
+
+// validators of the last loaded response
+type validators struct {
+	mu           sync.Mutex
+	etag         string
+	lastModified string
+}
+
+// New creates function loading all items from HTTP endpoint. Response body contains either a JSON
+// list of items or an object (map) of items (its keys are ignored, items are keyed by `KeyFunc`).
+// Duplicate keys are reported as error.
+//
+// `ETag` and `Last-Modified` of the last installed response are sent in conditional requests and
+// `304 Not Modified` response is returned as `codebook.ErrNotModified`, so the cache keeps its
+// current items. Validators are remembered by the returned function when the cache installs
+// loaded items (see `codebook.OnInstalled`), so each cache must use its own function.
+func New[K comparable, T any](params Params[K, T]) (loadAllFunc codebook.LoadAllFunc[K, T], err error) {
+	err = params.check()
+	if err != nil {
+		return
+	}
+
+	client := params.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	last := &validators{}
+	loadAllFunc = func(ctx context.Context) (entries map[K]*T, err error) {
+		start := time.Now()
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, params.URL, nil)
+		if err != nil {
+			return
+		}
+		for name, values := range params.Header {
+			request.Header[name] = values
+		}
+		request.Header.Set("Accept", "application/json")
+
+		last.mu.Lock()
+		if last.etag != "" {
+			request.Header.Set("If-None-Match", last.etag)
+		}
+		if last.lastModified != "" {
+			request.Header.Set("If-Modified-Since", last.lastModified)
+		}
+		last.mu.Unlock()
+
+		response, err := client.Do(request)
+		if err != nil {
+			return
+		}
+		defer response.Body.Close()
+
+		switch response.StatusCode {
+		case http.StatusOK:
+		case http.StatusNotModified:
+			return nil, codebook.ErrNotModified
+		default:
+			return nil, fmt.Errorf("unexpected response status %s", response.Status)
+		}
+
+		data, err := io.ReadAll(response.Body)
+		if err != nil {
+			return
+		}
+
+		items, err := decode.JSON[T](data)
+		if err != nil {
+			return
+		}
+
+		entries = make(map[K]*T, len(items))
+		for _, item := range items {
+			err = utils.AddEntry(entries, params.KeyFunc(item), item)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// items rejected, superseded or not installed by closed cache must be loaded again
+		etag, lastModified := response.Header.Get("ETag"), response.Header.Get("Last-Modified")
+		codebook.OnInstalled(ctx, func() {
+			last.mu.Lock()
+			last.etag = etag
+			last.lastModified = lastModified
+			last.mu.Unlock()
+		})
+
+		codebook.ReportLoadStats(ctx, codebook.LoadStats{
+			Rows:     len(items),
+			Duration: time.Since(start),
+		})
+
+		return
+	}
+
+	return
+}
@@ -0,0 +1,224 @@
+package httploader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	codebook "github.com/moderntv/codebook-cache"
+	"github.com/moderntv/codebook-cache/internal/test_utils"
+)
+
+type country struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+func countryCode(c *country) string {
+	return c.Code
+}
+
+// endpoint serves `body` with ETag `etag` and records request headers
+type endpoint struct {
+	mu       sync.Mutex
+	body     string
+	etag     string
+	status   int
+	requests []http.Header
+}
+
+func (e *endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.requests = append(e.requests, r.Header.Clone())
+	if e.status != 0 {
+		w.WriteHeader(e.status)
+		return
+	}
+
+	w.Header().Set("ETag", e.etag)
+	w.Header().Set("Last-Modified", "Wed, 14 Oct 2026 10:00:00 GMT")
+	if e.etag != "" && r.Header.Get("If-None-Match") == e.etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	_, _ = w.Write([]byte(e.body))
+}
+
+func (e *endpoint) set(body, etag string, status int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.body, e.etag, e.status = body, etag, status
+}
+
+func (e *endpoint) lastRequest() http.Header {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.requests[len(e.requests)-1]
+}
+
+func TestLoader(t *testing.T) {
+	t.Run("testLoaderDecode", testLoaderDecode)
+	t.Run("testLoaderConditional", testLoaderConditional)
+	t.Run("testLoaderErrors", testLoaderErrors)
+	t.Run("testLoaderCache", testLoaderCache)
+}
+
+func testLoaderDecode(t *testing.T) {
+	t.Parallel()
+
+	e := &endpoint{}
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	expected := map[string]*country{
+		"CZ": {Code: "CZ", Name: "Czechia"},
+		"NO": {Code: "NO", Name: "Norway"},
+	}
+
+	bodies := map[string]string{
+		"list":   `[{"code": "CZ", "name": "Czechia"}, {"code": "NO", "name": "Norway"}]`,
+		"object": `{"cz": {"code": "CZ", "name": "Czechia"}, "no": {"code": "NO", "name": "Norway"}}`,
+	}
+	for name, body := range bodies {
+		e.set(body, "", 0)
+		loadAllFunc, err := New(Params[string, country]{
+			URL:     server.URL,
+			Header:  http.Header{"Authorization": []string{"Bearer token"}},
+			KeyFunc: countryCode,
+		})
+		assert.NoError(t, err)
+
+		entries, err := loadAllFunc(context.Background())
+		assert.NoError(t, err, name)
+		assert.Equal(t, expected, entries, name)
+		assert.Equal(t, "Bearer token", e.lastRequest().Get("Authorization"))
+	}
+}
+
+func testLoaderConditional(t *testing.T) {
+	t.Parallel()
+
+	e := &endpoint{}
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	loadAllFunc, err := New(Params[string, country]{URL: server.URL, KeyFunc: countryCode})
+	assert.NoError(t, err)
+
+	e.set(`[{"code": "CZ", "name": "Czechia"}]`, `"v1"`, 0)
+	entries, err := loadAllFunc(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Empty(t, e.lastRequest().Get("If-None-Match"))
+
+	// validators of the last response are sent
+	_, err = loadAllFunc(context.Background())
+	assert.ErrorIs(t, err, codebook.ErrNotModified)
+	assert.Equal(t, `"v1"`, e.lastRequest().Get("If-None-Match"))
+	assert.Equal(t, "Wed, 14 Oct 2026 10:00:00 GMT", e.lastRequest().Get("If-Modified-Since"))
+
+	e.set(`[{"code": "CZ", "name": "Czechia"}, {"code": "NO", "name": "Norway"}]`, `"v2"`, 0)
+	entries, err = loadAllFunc(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	// failed request keeps validators
+	e.set("", `"v2"`, http.StatusBadGateway)
+	_, err = loadAllFunc(context.Background())
+	assert.EqualError(t, err, "unexpected response status 502 Bad Gateway")
+	e.set("", `"v2"`, 0)
+	_, err = loadAllFunc(context.Background())
+	assert.ErrorIs(t, err, codebook.ErrNotModified)
+	assert.Equal(t, `"v2"`, e.lastRequest().Get("If-None-Match"))
+}
+
+func testLoaderErrors(t *testing.T) {
+	t.Parallel()
+
+	e := &endpoint{}
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	_, err := New(Params[string, country]{KeyFunc: countryCode})
+	assert.EqualError(t, err, "URL must be set")
+	_, err = New(Params[string, country]{URL: server.URL})
+	assert.EqualError(t, err, "KeyFunc must be set")
+
+	loadAllFunc, err := New(Params[string, country]{URL: server.URL, KeyFunc: countryCode})
+	assert.NoError(t, err)
+
+	e.set(`[{"code": "CZ"}, {"code": "CZ"}]`, "", 0)
+	_, err = loadAllFunc(context.Background())
+	assert.EqualError(t, err, "duplicate key CZ")
+
+	e.set(`[{"code": "CZ"`, "", 0)
+	_, err = loadAllFunc(context.Background())
+	assert.EqualError(t, err, "unexpected end of JSON input")
+
+	e.set(`[null]`, "", 0)
+	_, err = loadAllFunc(context.Background())
+	assert.EqualError(t, err, "item 0 is null")
+}
+
+func testLoaderCache(t *testing.T) {
+	t.Parallel()
+
+	e := &endpoint{}
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	loadAllFunc, err := New(Params[string, country]{URL: server.URL, KeyFunc: countryCode})
+	assert.NoError(t, err)
+
+	e.set(`[{"code": "CZ", "name": "Czechia"}]`, `"v1"`, 0)
+	c, err := codebook.New(codebook.Params[string, country]{
+		Context:        context.Background(),
+		Log:            test_utils.Logger(),
+		Name:           "countries",
+		LoadAllFunc:    loadAllFunc,
+		MemsizeEnabled: true,
+		Validators:     []codebook.ValidateFunc[string, country]{codebook.RejectEmpty[string, country]()},
+		Timeouts: codebook.Timeouts{
+			ReloadInterval: 10 * time.Second,
+		},
+	})
+	assert.NoError(t, err)
+	defer c.Close(context.Background())
+
+	var changes []codebook.Changes[string]
+	c.Subscribe(func(ch codebook.Changes[string]) {
+		changes = append(changes, ch)
+	})
+
+	// not modified response keeps current items
+	entries := c.GetAll()
+	assert.NoError(t, c.InvalidateAndWait(context.Background()))
+	assert.Equal(t, reflect.ValueOf(entries).Pointer(), reflect.ValueOf(c.GetAll()).Pointer())
+	assert.Empty(t, changes)
+
+	e.set(`[{"code": "CZ", "name": "Czech Republic"}]`, `"v2"`, 0)
+	assert.NoError(t, c.InvalidateAndWait(context.Background()))
+	assert.Equal(t, "Czech Republic", c.Get("CZ").Name)
+	assert.Equal(t, []codebook.Changes[string]{{Modified: []string{"CZ"}}}, changes)
+
+	// validators of rejected response are not remembered, so it is requested again
+	e.set(`[]`, `"v3"`, 0)
+	assert.Error(t, c.InvalidateAndWait(context.Background()))
+	e.set(`[{"code": "CZ", "name": "Czechia"}]`, `"v3"`, 0)
+	assert.NoError(t, c.InvalidateAndWait(context.Background()))
+	assert.Equal(t, `"v2"`, e.lastRequest().Get("If-None-Match"))
+	assert.Equal(t, "Czechia", c.Get("CZ").Name)
+	assert.NoError(t, c.InvalidateAndWait(context.Background()))
+	assert.Equal(t, `"v3"`, e.lastRequest().Get("If-None-Match"))
+}
@@ -0,0 +1,110 @@
+package codebook
+
+import (
+	"fmt"
+)
+
+// Index is a secondary index over cached items. Indexes are declared in `Params.Indexes`
+// and are built once per successful load together with the primary data, so lookups
+// are lock-free and always consistent with `Get` and `GetAll`.
+//
+// Use `NewUniqueIndex` or `NewMultiIndex` to create an index.
+type Index[K comparable, T any] interface {
+	// Name returns name of the index which must be unique within one cache.
+	Name() string
+
+	build(entries map[K]*T) (any, error)
+}
+
+// UniqueIndex maps derived key to exactly one item.
+// When two items share the same derived key, the load fails and the previous data
+// are kept in cache.
+type UniqueIndex[K comparable, T any, I comparable] struct {
+	name    string
+	keyFunc func(entry *T) I
+}
+
+// NewUniqueIndex creates unique index named `name` with key derived from each item by `keyFunc`.
+func NewUniqueIndex[K comparable, T any, I comparable](name string, keyFunc func(entry *T) I) *UniqueIndex[K, T, I] {
+	return &UniqueIndex[K, T, I]{
+		name:    name,
+		keyFunc: keyFunc,
+	}
+}
+
+func (i *UniqueIndex[K, T, I]) Name() string {
+	return i.name
+}
+
+func (i *UniqueIndex[K, T, I]) build(entries map[K]*T) (any, error) {
+	index := make(map[I]*T, len(entries))
+	for _, entry := range entries {
+		key := i.keyFunc(entry)
+		if _, exists := index[key]; exists {
+			return nil, fmt.Errorf("index %q: duplicate key %v", i.name, key)
+		}
+
+		index[key] = entry
+	}
+
+	return index, nil
+}
+
+// Get returns item with given derived key or `nil` when no such item exists
+// (or index is not registered in cache `c`).
+func (i *UniqueIndex[K, T, I]) Get(c *Cache[K, T], key I) *T {
+	index, ok := c.loadData().indexes[i.name].(map[I]*T)
+	if !ok {
+		return nil
+	}
+
+	return c.cloneItem(index[key])
+}
+
+// MultiIndex maps derived key to all items sharing that key.
+type MultiIndex[K comparable, T any, I comparable] struct {
+	name    string
+	keyFunc func(entry *T) I
+}
+
+// NewMultiIndex creates non-unique index named `name` with key derived from each item by `keyFunc`.
+func NewMultiIndex[K comparable, T any, I comparable](name string, keyFunc func(entry *T) I) *MultiIndex[K, T, I] {
+	return &MultiIndex[K, T, I]{
+		name:    name,
+		keyFunc: keyFunc,
+	}
+}
+
+func (i *MultiIndex[K, T, I]) Name() string {
+	return i.name
+}
+
+func (i *MultiIndex[K, T, I]) build(entries map[K]*T) (any, error) {
+	index := make(map[I][]*T)
+	for _, entry := range entries {
+		key := i.keyFunc(entry)
+		index[key] = append(index[key], entry)
+	}
+
+	return index, nil
+}
+
+// Get returns all items with given derived key. Returned slice is shared and must not be modified
+// (with `Params.CloneOnRead` it is a new slice of cloned items). Order of items is not defined.
+func (i *MultiIndex[K, T, I]) Get(c *Cache[K, T], key I) (items []*T) {
+	index, ok := c.loadData().indexes[i.name].(map[I][]*T)
+	if !ok {
+		return nil
+	}
+
+	items = index[key]
+	if c.cloneFunc == nil || items == nil {
+		return
+	}
+
+	cloned := make([]*T, len(items))
+	for j, item := range items {
+		cloned[j] = c.cloneFunc(item)
+	}
+	return cloned
+}
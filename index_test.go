@@ -0,0 +1,132 @@
+package codebook
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/moderntv/codebook-cache/internal/test_utils"
+	"github.com/stretchr/testify/assert"
+)
+
+type testChannel struct {
+	ID      int
+	Slug    string
+	Package string
+}
+
+func TestIndex(t *testing.T) {
+	t.Run("testIndexLookup", testIndexLookup)
+	t.Run("testIndexDuplicateKey", testIndexDuplicateKey)
+	t.Run("testIndexParams", testIndexParams)
+}
+
+func testIndexLookup(t *testing.T) {
+	t.Parallel()
+
+	suffix := ""
+	bySlug := NewUniqueIndex[int]("slug", func(ch *testChannel) string { return ch.Slug + suffix })
+	byPackage := NewMultiIndex[int]("package", func(ch *testChannel) string { return ch.Package })
+
+	c, err := New(Params[int, testChannel]{
+		Context: context.Background(),
+		Log:     test_utils.Logger(),
+		Name:    "testing_cache",
+		LoadAllFunc: func(ctx context.Context) (map[int]*testChannel, error) {
+			return map[int]*testChannel{
+				1: {ID: 1, Slug: "one", Package: "basic"},
+				2: {ID: 2, Slug: "two", Package: "basic"},
+				3: {ID: 3, Slug: "three", Package: "premium"},
+			}, nil
+		},
+		Indexes: []Index[int, testChannel]{bySlug, byPackage},
+		Timeouts: Timeouts{
+			ReloadInterval: 5 * time.Second,
+		},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, bySlug.Get(c, "one").ID)
+	assert.Equal(t, 3, bySlug.Get(c, "three").ID)
+	assert.Nil(t, bySlug.Get(c, "four"))
+	assert.Len(t, byPackage.Get(c, "basic"), 2)
+	assert.Len(t, byPackage.Get(c, "premium"), 1)
+	assert.Empty(t, byPackage.Get(c, "none"))
+
+	// index is rebuilt together with data
+	suffix = "-new"
+	c.InvalidateAll()
+	time.Sleep(300 * time.Millisecond)
+
+	assert.Nil(t, bySlug.Get(c, "one"))
+	assert.Equal(t, 1, bySlug.Get(c, "one-new").ID)
+
+	// index not registered in cache
+	unknown := NewUniqueIndex[int]("unknown", func(ch *testChannel) string { return ch.Slug })
+	assert.Nil(t, unknown.Get(c, "one"))
+}
+
+func testIndexDuplicateKey(t *testing.T) {
+	t.Parallel()
+
+	duplicate := false
+	bySlug := NewUniqueIndex[int]("slug", func(ch *testChannel) string { return ch.Slug })
+
+	c, err := New(Params[int, testChannel]{
+		Context: context.Background(),
+		Log:     test_utils.Logger(),
+		Name:    "testing_cache",
+		LoadAllFunc: func(ctx context.Context) (map[int]*testChannel, error) {
+			entries := map[int]*testChannel{
+				1: {ID: 1, Slug: "one"},
+				2: {ID: 2, Slug: "two"},
+			}
+			if duplicate {
+				entries[3] = &testChannel{ID: 3, Slug: "one"}
+			}
+			return entries, nil
+		},
+		Indexes: []Index[int, testChannel]{bySlug},
+		Timeouts: Timeouts{
+			ReloadInterval: 5 * time.Second,
+		},
+	})
+	assert.NoError(t, err)
+
+	// duplicate key is treated as load failure -> old data are kept
+	duplicate = true
+	c.InvalidateAll()
+	time.Sleep(300 * time.Millisecond)
+
+	assert.Len(t, c.GetAll(), 2)
+	assert.Equal(t, 1, bySlug.Get(c, "one").ID)
+}
+
+func testIndexParams(t *testing.T) {
+	t.Parallel()
+
+	params := Params[int, testChannel]{
+		Context: context.Background(),
+		Name:    "testing_cache",
+		LoadAllFunc: func(ctx context.Context) (map[int]*testChannel, error) {
+			return nil, nil
+		},
+	}
+
+	params.Indexes = []Index[int, testChannel]{
+		NewUniqueIndex[int]("slug", func(ch *testChannel) string { return ch.Slug }),
+		NewMultiIndex[int]("slug", func(ch *testChannel) string { return ch.Package }),
+	}
+	assert.Error(t, params.check())
+
+	params.Indexes = []Index[int, testChannel]{
+		NewUniqueIndex[int]("", func(ch *testChannel) string { return ch.Slug }),
+	}
+	assert.Error(t, params.check())
+
+	params.Indexes = []Index[int, testChannel]{
+		NewUniqueIndex[int]("slug", func(ch *testChannel) string { return ch.Slug }),
+		NewMultiIndex[int]("package", func(ch *testChannel) string { return ch.Package }),
+	}
+	assert.NoError(t, params.check())
+}
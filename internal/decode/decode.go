@@ -0,0 +1,58 @@
+// Package decode decodes lists and objects of items shared by loaders.
+package decode
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// JSON decodes either a JSON list of items or an object (map) of items. Items of object are
+// returned sorted by their keys. Null items are reported as error.
+func JSON[T any](data []byte) (items []*T, err error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		var object map[string]*T
+		err = json.Unmarshal(data, &object)
+		if err != nil {
+			return
+		}
+		return ObjectItems(object)
+	}
+
+	err = json.Unmarshal(data, &items)
+	if err != nil {
+		return
+	}
+	return items, CheckItems(items)
+}
+
+// ObjectItems returns items of decoded object sorted by their keys. Null items are reported as error.
+func ObjectItems[T any](object map[string]*T) (items []*T, err error) {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	items = make([]*T, 0, len(object))
+	for _, key := range keys {
+		if object[key] == nil {
+			return nil, fmt.Errorf("item %q is null", key)
+		}
+		items = append(items, object[key])
+	}
+	return
+}
+
+// CheckItems reports null item of decoded list as error.
+func CheckItems[T any](items []*T) error {
+	for i, item := range items {
+		if item == nil {
+			return fmt.Errorf("item %d is null", i)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package decode
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type item struct {
+	Code string `json:"code"`
+}
+
+func TestJSON(t *testing.T) {
+	t.Run("List", func(t *testing.T) {
+		items, err := JSON[item]([]byte(` [{"code": "CZ"}, {"code": "NO"}]`))
+		assert.NoError(t, err)
+		assert.Equal(t, []*item{{Code: "CZ"}, {Code: "NO"}}, items)
+	})
+
+	t.Run("Object", func(t *testing.T) {
+		items, err := JSON[item]([]byte(`{"b": {"code": "NO"}, "a": {"code": "CZ"}}`))
+		assert.NoError(t, err)
+		assert.Equal(t, []*item{{Code: "CZ"}, {Code: "NO"}}, items)
+	})
+
+	t.Run("Null", func(t *testing.T) {
+		_, err := JSON[item]([]byte(`[{"code": "CZ"}, null]`))
+		assert.EqualError(t, err, "item 1 is null")
+
+		_, err = JSON[item]([]byte(`{"a": null}`))
+		assert.EqualError(t, err, `item "a" is null`)
+
+		items, err := JSON[item]([]byte(`null`))
+		assert.NoError(t, err)
+		assert.Empty(t, items)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := JSON[item]([]byte(`[{"code": "CZ"`))
+		assert.EqualError(t, err, "unexpected end of JSON input")
+	})
+}
@@ -2,6 +2,7 @@ package invalidation
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/nats-io/nats.go"
@@ -13,6 +14,11 @@ type NatsHelper struct {
 	log        zerolog.Logger
 	connection *nats.Conn
 	prefix     string
+	// attributes protected by mutex
+	mu            sync.Mutex
+	closed        bool
+	subscriptions []*nats.Subscription
+	retryTimers   []*time.Timer
 }
 
 type resetter interface {
@@ -53,10 +59,18 @@ func (h *NatsHelper) Publish(subject string, data proto.Message) (err error) {
 }
 
 // Subscribe receives messages from NATS and with each message
-// calls `cb` function.
+// calls `cb` function with decoded message and message headers (nil when message has no headers).
 // When Subscribe fails, function automatically tries to subscribe again
 // after 31 seconds until it succeeds.
-func (h *NatsHelper) Subscribe(subject string, protoMsg proto.Message, cb func(proto.Message)) {
+// Subscribe does nothing after Close has been called.
+func (h *NatsHelper) Subscribe(subject string, protoMsg proto.Message, cb func(proto.Message, nats.Header)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+
 	subs, err := h.connection.Subscribe(h.prefix+subject, func(natsMsg *nats.Msg) {
 		msgReset, ok := protoMsg.(resetter)
 		if ok {
@@ -77,7 +91,7 @@ func (h *NatsHelper) Subscribe(subject string, protoMsg proto.Message, cb func(p
 		h.log.Trace().
 			Str("subject", subject).
 			Msg("invalidation received")
-		cb(response)
+		cb(response, natsMsg.Header)
 	})
 	if err != nil {
 		h.log.Error().
@@ -86,8 +100,39 @@ func (h *NatsHelper) Subscribe(subject string, protoMsg proto.Message, cb func(p
 			Msg("cannot subscribe to NATS server")
 		_ = subs.Unsubscribe() // ignore error
 		// try to subscribe again after 31 sec
-		time.AfterFunc(31*time.Second, func() {
+		timer := time.AfterFunc(31*time.Second, func() {
 			h.Subscribe(subject, protoMsg, cb)
 		})
+		h.retryTimers = append(h.retryTimers, timer)
+		return
+	}
+
+	h.subscriptions = append(h.subscriptions, subs)
+}
+
+// Close unsubscribes all subscriptions and stops all pending subscribe retries.
+func (h *NatsHelper) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+	h.closed = true
+
+	for _, timer := range h.retryTimers {
+		timer.Stop()
+	}
+	h.retryTimers = nil
+
+	for _, subs := range h.subscriptions {
+		err := subs.Unsubscribe()
+		if err != nil {
+			h.log.Warn().
+				Err(err).
+				Str("subject", subs.Subject).
+				Msg("cannot unsubscribe from NATS server")
+		}
 	}
+	h.subscriptions = nil
 }
@@ -1,6 +1,12 @@
 package metrics
 
 import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+
 	cadre_metrics "github.com/moderntv/cadre/metrics"
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -9,74 +15,305 @@ const (
 	metricsPrefix = "cache_"
 	subSystem     = "codebook_cache"
 	labelName     = "name"
+	// not `instance`, it is target label set by prometheus on scrape
+	labelInstance = "cache_instance"
+	labelReason   = "reason"
+	labelResult   = "result"
+	labelSource   = "source"
+)
+
+// values of result label
+const (
+	ResultSuccess   = "success"
+	ResultFailure   = "failure"
+	ResultCancelled = "cancelled"
+	// ResultNotModified is successful load which kept current items (data source reported no change)
+	ResultNotModified = "not_modified"
 )
 
+var (
+	// registryMu serializes access to registries (cadre registry is not safe for concurrent use)
+	// and to shared vectors
+	registryMu sync.Mutex
+	// shared vectors by registry key (pointer to registry), registerers registering into the same
+	// underlying registry (e.g. two wrapping registerers with the same prefix) share the same vectors
+	shared = map[any]*vectors{}
+)
+
+// Metrics of one cache. Metrics are children of vectors shared by all caches using the same registry.
+// Caches with the same name are distinguished by `cache_instance` label (the lowest number not used
+// by other cache with the same name).
 type Metrics struct {
 	ItemsCount                prometheus.Gauge
 	LoadCount                 prometheus.Counter
+	LoadFailureCount          *prometheus.CounterVec // labels: reason
+	LoadDuration              prometheus.ObserverVec // labels: reason, result
+	LastSuccessTimestamp      prometheus.Gauge
+	RejectedLoadCount         prometheus.Counter
 	ReloadInterval            prometheus.Gauge
+	ReceivedInvalidations     *prometheus.CounterVec // labels: source
 	ReceivedNatsInvalidations prometheus.Counter
+	CoalescedInvalidations    prometheus.Counter
+	TimedOutLoadCount         prometheus.Counter
+	CancelledLoadCount        prometheus.Counter
 	MemoryUsage               prometheus.Gauge
+	SourceRowCount            prometheus.Counter
+	SourceQueryDuration       prometheus.Observer
+
+	name     string
+	instance string
+	vectors  *vectors
+	once     sync.Once
 }
 
+// New creates metrics of cache `name` registered into cadre registry.
 func New(
 	name string,
 	registry *cadre_metrics.Registry,
 ) (m *Metrics, err error) {
-	itemsCount := registry.NewGauge(prometheus.GaugeOpts{
-		Subsystem:   subSystem,
-		Name:        "items_count",
-		Help:        "Count of cached items",
-		ConstLabels: prometheus.Labels{labelName: name},
-	})
+	return newMetrics(name, newCadreRegistry(registry))
+}
 
-	loadCount := registry.NewCounter(prometheus.CounterOpts{
-		Subsystem:   subSystem,
-		Name:        "load_count",
-		Help:        "Total number of complete loads",
-		ConstLabels: prometheus.Labels{labelName: name},
-	})
+// NewWithRegisterer creates metrics of cache `name` registered into prometheus registerer.
+func NewWithRegisterer(
+	name string,
+	registerer prometheus.Registerer,
+) (m *Metrics, err error) {
+	// registerer identifies shared vectors, values of other types need not be comparable
+	if reflect.ValueOf(registerer).Kind() != reflect.Pointer {
+		err = errors.New("metrics registerer must be a pointer")
+		return
+	}
 
-	receivedNatsInvalidations := registry.NewCounter(prometheus.CounterOpts{
-		Subsystem:   subSystem,
-		Name:        "received_nats_invalidations",
-		Help:        "Total number of received invalidations",
-		ConstLabels: prometheus.Labels{labelName: name},
-	})
+	return newMetrics(name, prometheusRegistry{registerer: registerer})
+}
 
-	memoryUsage := registry.NewGauge(prometheus.GaugeOpts{
-		Subsystem:   subSystem,
-		Name:        "memory_usage",
-		Help:        "Current memory usage in bytes by entries",
-		ConstLabels: prometheus.Labels{labelName: name},
+func newMetrics(name string, registry registry) (m *Metrics, err error) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	v, exists := shared[registry.key()]
+	if !exists {
+		v, err = newVectors(registry)
+		if err != nil {
+			return
+		}
+		// vectors registered already by another registerer are reused, so instances must be shared too
+		if other := sharedWith(v); other != nil {
+			v.unregisterLocked()
+			v = other
+		}
+		shared[registry.key()] = v
+	}
+	instance := v.addInstance(name)
+
+	curried := prometheus.Labels{labelName: name, labelInstance: instance}
+	m = &Metrics{
+		ItemsCount:                v.itemsCount.WithLabelValues(name, instance),
+		LoadCount:                 v.loadCount.WithLabelValues(name, instance),
+		LoadFailureCount:          v.loadFailureCount.MustCurryWith(curried),
+		LoadDuration:              v.loadDuration.MustCurryWith(curried),
+		LastSuccessTimestamp:      v.lastSuccessTimestamp.WithLabelValues(name, instance),
+		RejectedLoadCount:         v.rejectedLoadCount.WithLabelValues(name, instance),
+		ReloadInterval:            v.reloadInterval.WithLabelValues(name, instance),
+		ReceivedInvalidations:     v.receivedInvalidations.MustCurryWith(curried),
+		ReceivedNatsInvalidations: v.receivedNatsInvalidations.WithLabelValues(name, instance),
+		CoalescedInvalidations:    v.coalescedInvalidations.WithLabelValues(name, instance),
+		TimedOutLoadCount:         v.timedOutLoadCount.WithLabelValues(name, instance),
+		CancelledLoadCount:        v.cancelledLoadCount.WithLabelValues(name, instance),
+		MemoryUsage:               v.memoryUsage.WithLabelValues(name, instance),
+		SourceRowCount:            v.sourceRowCount.WithLabelValues(name, instance),
+		SourceQueryDuration:       v.sourceQueryDuration.WithLabelValues(name, instance),
+		name:                      name,
+		instance:                  instance,
+		vectors:                   v,
+	}
+
+	return
+}
+
+// Unregister removes metrics of the cache. Vectors are unregistered from registry
+// when no other cache uses them. Calling Unregister more than once is safe.
+func (m *Metrics) Unregister() {
+	m.once.Do(func() {
+		registryMu.Lock()
+		defer registryMu.Unlock()
+
+		v := m.vectors
+		v.removeInstance(m.name, m.instance)
+		for _, c := range v.collectors {
+			c.vec.DeletePartialMatch(prometheus.Labels{labelName: m.name, labelInstance: m.instance})
+		}
+
+		if len(v.instances) == 0 {
+			v.unregisterLocked()
+			for key, other := range shared {
+				if other == v {
+					delete(shared, key)
+				}
+			}
+		}
 	})
+}
 
-	err = registry.Register(metricsPrefix+name+"_items_count", itemsCount)
-	if err != nil {
-		return
+// sharedWith returns other shared vectors using the same collectors as `v` (nil when there are none)
+func sharedWith(v *vectors) *vectors {
+	for _, other := range shared {
+		if other.itemsCount == v.itemsCount {
+			return other
+		}
 	}
+	return nil
+}
 
-	err = registry.Register(metricsPrefix+name+"_load_count", loadCount)
-	if err != nil {
-		return
+// vectors are collectors labeled by cache name
+type vectors struct {
+	itemsCount                *prometheus.GaugeVec
+	loadCount                 *prometheus.CounterVec
+	loadFailureCount          *prometheus.CounterVec
+	loadDuration              *prometheus.HistogramVec
+	lastSuccessTimestamp      *prometheus.GaugeVec
+	rejectedLoadCount         *prometheus.CounterVec
+	reloadInterval            *prometheus.GaugeVec
+	receivedInvalidations     *prometheus.CounterVec
+	receivedNatsInvalidations *prometheus.CounterVec
+	coalescedInvalidations    *prometheus.CounterVec
+	timedOutLoadCount         *prometheus.CounterVec
+	cancelledLoadCount        *prometheus.CounterVec
+	memoryUsage               *prometheus.GaugeVec
+	sourceRowCount            *prometheus.CounterVec
+	sourceQueryDuration       *prometheus.HistogramVec
+
+	// registry the vectors were registered into
+	registry   registry
+	collectors []vectorCollector
+	// instances of caches using vectors by cache name
+	instances map[string]map[string]struct{}
+}
+
+// addInstance returns the lowest instance number not used by other cache named `name`
+func (v *vectors) addInstance(name string) (instance string) {
+	used, exists := v.instances[name]
+	if !exists {
+		used = map[string]struct{}{}
+		v.instances[name] = used
+	}
+
+	for i := 0; ; i++ {
+		instance = strconv.Itoa(i)
+		if _, exists := used[instance]; !exists {
+			used[instance] = struct{}{}
+			return
+		}
+	}
+}
+
+func (v *vectors) removeInstance(name, instance string) {
+	delete(v.instances[name], instance)
+	if len(v.instances[name]) == 0 {
+		delete(v.instances, name)
+	}
+}
+
+type vectorCollector struct {
+	name string
+	vec  interface {
+		prometheus.Collector
+		DeletePartialMatch(labels prometheus.Labels) int
+	}
+	// collector was registered by this package (not reused)
+	owned bool
+}
+
+func newVectors(registry registry) (v *vectors, err error) {
+	nameLabels := []string{labelName, labelInstance}
+
+	v = &vectors{
+		registry:  registry,
+		instances: map[string]map[string]struct{}{},
 	}
 
-	err = registry.Register(metricsPrefix+name+"_received_nats_invalidations", receivedNatsInvalidations)
+	gaugeVec := func(target **prometheus.GaugeVec, name, help string) {
+		if err != nil {
+			return
+		}
+		vec := registry.newGaugeVec(prometheus.GaugeOpts{Subsystem: subSystem, Name: name, Help: help}, nameLabels)
+		*target, err = register(v, registry, name, vec)
+	}
+	counterVec := func(target **prometheus.CounterVec, name, help string, labels ...string) {
+		if err != nil {
+			return
+		}
+		vec := registry.newCounterVec(prometheus.CounterOpts{Subsystem: subSystem, Name: name, Help: help}, append(nameLabels, labels...))
+		*target, err = register(v, registry, name, vec)
+	}
+
+	gaugeVec(&v.itemsCount, "items_count", "Count of cached items")
+	counterVec(&v.loadCount, "load_count", "Total number of complete loads")
+	counterVec(&v.loadFailureCount, "load_failure_count", "Total number of failed loads by reload reason", labelReason)
+	histogramVec := func(target **prometheus.HistogramVec, name, help string, labels ...string) {
+		if err != nil {
+			return
+		}
+		vec := registry.newHistogramVec(prometheus.HistogramOpts{
+			Subsystem: subSystem,
+			Name:      name,
+			Help:      help,
+			Buckets:   prometheus.ExponentialBuckets(0.005, 4, 9),
+		}, append(nameLabels, labels...))
+		*target, err = register(v, registry, name, vec)
+	}
+
+	histogramVec(&v.loadDuration, "load_duration_seconds", "Duration of loads by reload reason and result", labelReason, labelResult)
+	gaugeVec(&v.lastSuccessTimestamp, "last_success_timestamp_seconds", "Unix time when the last successful load started")
+	counterVec(&v.rejectedLoadCount, "rejected_load_count", "Total number of loads rejected by validation")
+	gaugeVec(&v.reloadInterval, "reload_interval_seconds", "Configured interval of periodic reloads (0 when disabled)")
+	counterVec(&v.receivedInvalidations, "received_invalidations", "Total number of received invalidations by invalidation source", labelSource)
+	counterVec(&v.receivedNatsInvalidations, "received_nats_invalidations", "Total number of received NATS invalidations")
+	counterVec(&v.coalescedInvalidations, "coalesced_invalidations", "Total number of invalidations received during running reload and coalesced into one following reload")
+	counterVec(&v.timedOutLoadCount, "timed_out_load_count", "Total number of loads cancelled after load timeout")
+	counterVec(&v.cancelledLoadCount, "cancelled_load_count", "Total number of loads cancelled and restarted due to invalidation received during load")
+	gaugeVec(&v.memoryUsage, "memory_usage", "Current memory usage in bytes by entries")
+	counterVec(&v.sourceRowCount, "source_row_count", "Total number of rows read from data source by load functions")
+	histogramVec(&v.sourceQueryDuration, "source_query_duration_seconds", "Duration of data source queries performed by load functions")
+
 	if err != nil {
-		return
+		v.unregisterLocked()
+		v = nil
 	}
 
-	err = registry.Register(metricsPrefix+name+"_memory_usage", memoryUsage)
+	return
+}
+
+// register registers `vec` or returns already registered collector with the same name
+func register[V interface {
+	prometheus.Collector
+	DeletePartialMatch(labels prometheus.Labels) int
+}](v *vectors, registry registry, name string, vec V) (registered V, err error) {
+	c, err := registry.register(name, vec)
 	if err != nil {
+		err = fmt.Errorf("cannot register metric %s: %w", name, err)
 		return
 	}
 
-	m = &Metrics{
-		ItemsCount:                itemsCount,
-		LoadCount:                 loadCount,
-		ReceivedNatsInvalidations: receivedNatsInvalidations,
-		MemoryUsage:               memoryUsage,
+	registered, ok := c.(V)
+	if !ok {
+		err = fmt.Errorf("metric %s already registered with different type", name)
+		return
 	}
 
+	v.collectors = append(v.collectors, vectorCollector{
+		name:  name,
+		vec:   registered,
+		owned: c == prometheus.Collector(vec),
+	})
 	return
 }
+
+func (v *vectors) unregisterLocked() {
+	for _, c := range v.collectors {
+		if c.owned {
+			v.registry.unregister(c.name, c.vec)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package metrics
+
+import (
+	"testing"
+
+	cadre_metrics "github.com/moderntv/cadre/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Run("CadreNamespace", testMetricsCadreNamespace)
+	t.Run("Shared", testMetricsShared)
+	t.Run("Instances", testMetricsInstances)
+	t.Run("Registerer", testMetricsRegisterer)
+	t.Run("WrappedRegisterers", testMetricsWrappedRegisterers)
+}
+
+// families returns names of gathered metric families
+func families(t *testing.T, gatherer prometheus.Gatherer) (names []string) {
+	gathered, err := gatherer.Gather()
+	assert.NoError(t, err)
+
+	for _, family := range gathered {
+		names = append(names, family.GetName())
+	}
+	return
+}
+
+func testMetricsCadreNamespace(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	cadreRegistry, err := cadre_metrics.NewRegistry("testing", registry)
+	assert.NoError(t, err)
+
+	m, err := New("cache", cadreRegistry)
+	assert.NoError(t, err)
+	defer m.Unregister()
+
+	m.LoadDuration.WithLabelValues("manual", ResultSuccess).Observe(1)
+	m.SourceQueryDuration.Observe(1)
+	m.ItemsCount.Set(1)
+
+	names := families(t, registry)
+	assert.Contains(t, names, "testing_codebook_cache_load_duration_seconds")
+	assert.Contains(t, names, "testing_codebook_cache_source_query_duration_seconds")
+	assert.Contains(t, names, "testing_codebook_cache_items_count")
+
+	t.Run("EmptyNamespace", func(t *testing.T) {
+		registry := prometheus.NewRegistry()
+		cadreRegistry, err := cadre_metrics.NewRegistry("", registry)
+		assert.NoError(t, err)
+
+		m, err := New("cache", cadreRegistry)
+		assert.NoError(t, err)
+		defer m.Unregister()
+
+		m.LoadDuration.WithLabelValues("manual", ResultSuccess).Observe(1)
+		assert.Contains(t, families(t, registry), "codebook_cache_load_duration_seconds")
+	})
+}
+
+func testMetricsShared(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	first, err := NewWithRegisterer("first", registry)
+	assert.NoError(t, err)
+	second, err := NewWithRegisterer("second", registry)
+	assert.NoError(t, err)
+	assert.Same(t, first.vectors, second.vectors)
+
+	first.ItemsCount.Set(1)
+	first.LoadFailureCount.WithLabelValues("manual").Inc()
+	second.ItemsCount.Set(2)
+	second.LoadFailureCount.WithLabelValues("manual").Inc()
+	assert.Equal(t, 2, testutil.CollectAndCount(registry, "codebook_cache_items_count"))
+	assert.Equal(t, 2, testutil.CollectAndCount(registry, "codebook_cache_load_failure_count"))
+
+	// series of the other cache survive
+	first.Unregister()
+	first.Unregister()
+	assert.Equal(t, 1, testutil.CollectAndCount(registry, "codebook_cache_items_count"))
+	assert.Equal(t, 1, testutil.CollectAndCount(registry, "codebook_cache_load_failure_count"))
+	assert.Equal(t, float64(2), testutil.ToFloat64(second.ItemsCount))
+
+	// the last cache unregisters all vectors
+	second.Unregister()
+	assert.Equal(t, 0, testutil.CollectAndCount(registry))
+	assert.NotContains(t, shared, prometheusRegistry{registerer: registry}.key())
+
+	// vectors can be registered again
+	third, err := NewWithRegisterer("first", registry)
+	assert.NoError(t, err)
+	defer third.Unregister()
+	assert.NotSame(t, first.vectors, third.vectors)
+	third.ItemsCount.Set(3)
+	assert.Equal(t, 1, testutil.CollectAndCount(registry, "codebook_cache_items_count"))
+}
+
+func testMetricsInstances(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	first, err := NewWithRegisterer("cache", registry)
+	assert.NoError(t, err)
+	second, err := NewWithRegisterer("cache", registry)
+	assert.NoError(t, err)
+	assert.Equal(t, "0", first.instance)
+	assert.Equal(t, "1", second.instance)
+
+	first.ItemsCount.Set(1)
+	second.ItemsCount.Set(2)
+	assert.Equal(t, float64(1), testutil.ToFloat64(first.ItemsCount))
+	assert.Equal(t, float64(2), testutil.ToFloat64(second.ItemsCount))
+
+	// instance of closed cache is reused
+	first.Unregister()
+	third, err := NewWithRegisterer("cache", registry)
+	assert.NoError(t, err)
+	assert.Equal(t, "0", third.instance)
+
+	second.Unregister()
+	third.Unregister()
+	assert.Equal(t, 0, testutil.CollectAndCount(registry))
+}
+
+// valueRegisterer is registerer of non-comparable type
+type valueRegisterer struct {
+	prometheus.Registerer
+	labels []string
+}
+
+func testMetricsRegisterer(t *testing.T) {
+	_, err := NewWithRegisterer("cache", valueRegisterer{Registerer: prometheus.NewRegistry()})
+	assert.Error(t, err)
+
+	// wrapping registerer is a pointer
+	registry := prometheus.NewRegistry()
+	m, err := NewWithRegisterer("cache", prometheus.WrapRegistererWithPrefix("app_", registry))
+	assert.NoError(t, err)
+	defer m.Unregister()
+
+	m.ItemsCount.Set(1)
+	assert.Contains(t, families(t, registry), "app_codebook_cache_items_count")
+}
+
+func testMetricsWrappedRegisterers(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	// different registerers registering into the same registry
+	first, err := NewWithRegisterer("channels", prometheus.WrapRegistererWithPrefix("svc_", registry))
+	assert.NoError(t, err)
+	second, err := NewWithRegisterer("channels", prometheus.WrapRegistererWithPrefix("svc_", registry))
+	assert.NoError(t, err)
+	assert.Same(t, first.vectors, second.vectors)
+	assert.Equal(t, "0", first.instance)
+	assert.Equal(t, "1", second.instance)
+
+	first.ItemsCount.Set(1)
+	second.ItemsCount.Set(2)
+	assert.Equal(t, 2, testutil.CollectAndCount(registry, "svc_codebook_cache_items_count"))
+
+	// series of the other cache survive
+	first.Unregister()
+	assert.Equal(t, 1, testutil.CollectAndCount(registry, "svc_codebook_cache_items_count"))
+	assert.Equal(t, float64(2), testutil.ToFloat64(second.ItemsCount))
+
+	second.Unregister()
+	assert.Equal(t, 0, testutil.CollectAndCount(registry))
+	for _, v := range shared {
+		assert.NotSame(t, first.vectors, v)
+	}
+}
@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"errors"
+	"strings"
+
+	cadre_metrics "github.com/moderntv/cadre/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// registry creates and registers collectors into cadre registry or prometheus registerer
+type registry interface {
+	// key identifies registry in shared vectors (comparable by pointer identity)
+	key() any
+	newGaugeVec(opts prometheus.GaugeOpts, labels []string) *prometheus.GaugeVec
+	newCounterVec(opts prometheus.CounterOpts, labels []string) *prometheus.CounterVec
+	newHistogramVec(opts prometheus.HistogramOpts, labels []string) *prometheus.HistogramVec
+	// register registers collector `c` under `name` or returns already registered collector
+	register(name string, c prometheus.Collector) (prometheus.Collector, error)
+	unregister(name string, c prometheus.Collector)
+}
+
+type cadreRegistry struct {
+	registry  *cadre_metrics.Registry
+	namespace string
+}
+
+// namespaceProbe is name of collector used to read namespace of cadre registry
+const namespaceProbe = "namespace_probe"
+
+func newCadreRegistry(registry *cadre_metrics.Registry) cadreRegistry {
+	return cadreRegistry{
+		registry:  registry,
+		namespace: cadreNamespace(registry),
+	}
+}
+
+// cadreNamespace returns namespace of cadre registry. Registry does not expose it, so it is read
+// from name of (unregistered) collector created by the registry.
+func cadreNamespace(registry *cadre_metrics.Registry) string {
+	probe := prometheus.NewRegistry()
+	err := probe.Register(registry.NewGauge(prometheus.GaugeOpts{Name: namespaceProbe}))
+	if err != nil {
+		return ""
+	}
+
+	families, err := probe.Gather()
+	if err != nil || len(families) != 1 {
+		return ""
+	}
+
+	return strings.TrimSuffix(strings.TrimSuffix(families[0].GetName(), namespaceProbe), "_")
+}
+
+func (r cadreRegistry) key() any {
+	return r.registry
+}
+
+func (r cadreRegistry) newGaugeVec(opts prometheus.GaugeOpts, labels []string) *prometheus.GaugeVec {
+	return r.registry.NewGaugeVec(opts, labels)
+}
+
+func (r cadreRegistry) newCounterVec(opts prometheus.CounterOpts, labels []string) *prometheus.CounterVec {
+	return r.registry.NewCounterVec(opts, labels)
+}
+
+// newHistogramVec creates histogram with registry namespace (cadre registry has no histogram constructor)
+func (r cadreRegistry) newHistogramVec(opts prometheus.HistogramOpts, labels []string) *prometheus.HistogramVec {
+	opts.Namespace = r.namespace
+
+	return prometheus.NewHistogramVec(opts, labels)
+}
+
+func (r cadreRegistry) register(name string, c prometheus.Collector) (prometheus.Collector, error) {
+	return r.registry.RegisterOrGet(metricsPrefix+name, c)
+}
+
+func (r cadreRegistry) unregister(name string, _ prometheus.Collector) {
+	_ = r.registry.Unregister(metricsPrefix + name) // ignore error - metric is not registered
+}
+
+type prometheusRegistry struct {
+	registerer prometheus.Registerer
+}
+
+func (r prometheusRegistry) key() any {
+	return r.registerer
+}
+
+func (r prometheusRegistry) newGaugeVec(opts prometheus.GaugeOpts, labels []string) *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(opts, labels)
+}
+
+func (r prometheusRegistry) newCounterVec(opts prometheus.CounterOpts, labels []string) *prometheus.CounterVec {
+	return prometheus.NewCounterVec(opts, labels)
+}
+
+func (r prometheusRegistry) newHistogramVec(opts prometheus.HistogramOpts, labels []string) *prometheus.HistogramVec {
+	return prometheus.NewHistogramVec(opts, labels)
+}
+
+func (r prometheusRegistry) register(_ string, c prometheus.Collector) (prometheus.Collector, error) {
+	err := r.registerer.Register(c)
+
+	var registeredErr prometheus.AlreadyRegisteredError
+	if errors.As(err, &registeredErr) {
+		return registeredErr.ExistingCollector, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (r prometheusRegistry) unregister(_ string, c prometheus.Collector) {
+	r.registerer.Unregister(c)
+}
@@ -0,0 +1,15 @@
+package utils
+
+import (
+	"fmt"
+)
+
+// AddEntry adds `item` with `key` into `entries`. Duplicate key is reported as error.
+func AddEntry[K comparable, T any](entries map[K]*T, key K, item *T) error {
+	if _, exists := entries[key]; exists {
+		return fmt.Errorf("duplicate key %v", key)
+	}
+
+	entries[key] = item
+	return nil
+}
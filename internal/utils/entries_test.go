@@ -0,0 +1,17 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddEntry(t *testing.T) {
+	one, two := 1, 2
+	entries := map[string]*int{}
+
+	assert.NoError(t, AddEntry(entries, "one", &one))
+	assert.NoError(t, AddEntry(entries, "two", &two))
+	assert.EqualError(t, AddEntry(entries, "one", &two), "duplicate key one")
+	assert.Equal(t, map[string]*int{"one": &one, "two": &two}, entries)
+}
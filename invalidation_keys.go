@@ -0,0 +1,102 @@
+package codebook
+
+import (
+	"context"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// LoadByKeysFunc loads items with given keys. Keys missing in returned `entries` are treated as deleted.
+type LoadByKeysFunc[K comparable, T any] func(ctx context.Context, keys []K) (entries map[K]*T, err error)
+
+// KeyExtractor returns keys of items invalidated by invalidation message.
+type KeyExtractor[K comparable] func(msg proto.Message) []K
+
+// InvalidateKeys triggers reload of items with given keys (immediate or delayed depending on
+// `Timeouts.ReloadDelay` value, keys are aggregated during the delay). When `Params.LoadByKeysFunc`
+// is not set, all items are reloaded as with `InvalidateAll`.
+func (c *Cache[K, T]) InvalidateKeys(keys ...K) {
+	c.invalidateKeys(reasonManual, keys)
+}
+
+func (c *Cache[K, T]) invalidateKeys(reason reloadReason, keys []K) {
+	if c.loadByKeysFunc == nil {
+		c.invalidateAll(reason)
+		return
+	}
+
+	if len(keys) == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.addPendingKeysLocked(keys)
+	c.mu.Unlock()
+
+	c.invalidate(reason)
+}
+
+func (c *Cache[K, T]) addPendingKeys(keys []K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.addPendingKeysLocked(keys)
+}
+
+func (c *Cache[K, T]) addPendingKeysLocked(keys []K) {
+	if c.pendingKeys == nil {
+		c.pendingKeys = make(map[K]struct{}, len(keys))
+	}
+	for _, key := range keys {
+		c.pendingKeys[key] = struct{}{}
+	}
+}
+
+// takePendingKeys clears pending invalidations and returns keys which should be reloaded.
+// Returns nil when all items should be reloaded: not `invalidation` reload, whole repository
+// was invalidated, cache is stale or there are no pending keys.
+func (c *Cache[K, T]) takePendingKeys(invalidation bool) (keys []K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if invalidation && !c.pendingFull && len(c.pendingKeys) > 0 && !c.stale.Load() {
+		keys = make([]K, 0, len(c.pendingKeys))
+		for key := range c.pendingKeys {
+			keys = append(keys, key)
+		}
+	}
+
+	c.pendingFull = false
+	c.pendingKeys = nil
+	return
+}
+
+// loadKeys loads items with given keys and applies them on copy of the current items
+// (the current items are never modified)
+func (c *Cache[K, T]) loadKeys(ctx context.Context, keys []K) (data *dataset[K, T], err error) {
+	current := c.loadData()
+
+	loaded, err := c.loadByKeysFunc(ctx, keys)
+	if err != nil {
+		return
+	}
+
+	entries := make(map[K]*T, len(current.entries)+len(loaded))
+	for key, entry := range current.entries {
+		entries[key] = entry
+	}
+	for _, key := range keys {
+		entry, exists := loaded[key]
+		if exists {
+			entries[key] = entry
+		} else {
+			delete(entries, key)
+		}
+	}
+
+	return c.buildDataset(entries, current.version)
+}
@@ -0,0 +1,220 @@
+package codebook
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/moderntv/codebook-cache/internal/test_utils"
+)
+
+func TestInvalidationKeys(t *testing.T) {
+	t.Run("testInvalidationKeysAggregated", testInvalidationKeysAggregated)
+	t.Run("testInvalidationKeysMessages", testInvalidationKeysMessages)
+	t.Run("testInvalidationKeysParams", testInvalidationKeysParams)
+}
+
+// keyedSource counts calls of loading functions
+type keyedSource struct {
+	mu         sync.Mutex
+	entries    map[string]*int
+	fullLoads  int
+	loadedKeys [][]string
+}
+
+func (s *keyedSource) set(key string, value *int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if value == nil {
+		delete(s.entries, key)
+		return
+	}
+	s.entries[key] = value
+}
+
+func (s *keyedSource) loadAll(ctx context.Context) (map[string]*int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fullLoads++
+	entries := make(map[string]*int, len(s.entries))
+	for key, value := range s.entries {
+		entries[key] = value
+	}
+	return entries, nil
+}
+
+func (s *keyedSource) loadByKeys(ctx context.Context, keys []string) (map[string]*int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sorted := append([]string{}, keys...)
+	sort.Strings(sorted)
+	s.loadedKeys = append(s.loadedKeys, sorted)
+
+	entries := make(map[string]*int)
+	for _, key := range keys {
+		if value, exists := s.entries[key]; exists {
+			entries[key] = value
+		}
+	}
+	return entries, nil
+}
+
+func (s *keyedSource) stats() (int, [][]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.fullLoads, s.loadedKeys
+}
+
+func newKeyedSource() *keyedSource {
+	return &keyedSource{
+		entries: map[string]*int{
+			"key1": test_utils.IntPointer(1),
+			"key2": test_utils.IntPointer(2),
+			"key3": test_utils.IntPointer(3),
+		},
+	}
+}
+
+func testInvalidationKeysAggregated(t *testing.T) {
+	t.Parallel()
+
+	source := newKeyedSource()
+	c, err := New(Params[string, int]{
+		Context:        context.Background(),
+		Log:            test_utils.Logger(),
+		Name:           "testing_cache",
+		LoadAllFunc:    source.loadAll,
+		LoadByKeysFunc: source.loadByKeys,
+		Timeouts: Timeouts{
+			ReloadInterval: 5 * time.Second,
+			ReloadDelay:    500 * time.Millisecond,
+		},
+	})
+	assert.NoError(t, err)
+
+	before := c.GetAll()
+
+	// first invalidation is processed immediately
+	source.set("key1", test_utils.IntPointer(10))
+	c.InvalidateKeys("key1")
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, test_utils.IntPointer(10), c.Get("key1"))
+
+	// following invalidations are aggregated
+	source.set("key2", nil)
+	source.set("key4", test_utils.IntPointer(4))
+	c.InvalidateKeys("key2")
+	c.InvalidateKeys("key4", "key2")
+	time.Sleep(600 * time.Millisecond)
+
+	fullLoads, loadedKeys := source.stats()
+	assert.Equal(t, 1, fullLoads)
+	assert.Equal(t, [][]string{{"key1"}, {"key2", "key4"}}, loadedKeys)
+	assert.Equal(t, map[string]*int{
+		"key1": test_utils.IntPointer(10),
+		"key3": test_utils.IntPointer(3),
+		"key4": test_utils.IntPointer(4),
+	}, c.GetAll())
+
+	// previously returned items are not modified
+	assert.Len(t, before, 3)
+	assert.Equal(t, test_utils.IntPointer(1), before["key1"])
+
+	// invalidation of all items takes precedence
+	c.InvalidateKeys("key3")
+	c.InvalidateAll()
+	time.Sleep(600 * time.Millisecond)
+	fullLoads, loadedKeys = source.stats()
+	assert.Equal(t, 2, fullLoads)
+	assert.Len(t, loadedKeys, 2)
+}
+
+func testInvalidationKeysMessages(t *testing.T) {
+	t.Parallel()
+
+	source := newKeyedSource()
+	c, err := New(Params[string, int]{
+		Context:        context.Background(),
+		Log:            test_utils.Logger(),
+		Name:           "testing_cache",
+		LoadAllFunc:    source.loadAll,
+		LoadByKeysFunc: source.loadByKeys,
+		Invalidations: &Invalidations{
+			Nats: test_utils.NatsConnection(t),
+			Messages: map[string]proto.Message{
+				"keyed":   &wrapperspb.StringValue{},
+				"unkeyed": &wrapperspb.StringValue{},
+			},
+		},
+		InvalidationKeys: map[string]KeyExtractor[string]{
+			"keyed": func(msg proto.Message) []string {
+				value := msg.(*wrapperspb.StringValue).GetValue()
+				if value == "" {
+					return nil
+				}
+				return []string{value}
+			},
+		},
+		Timeouts: Timeouts{
+			ReloadInterval: 5 * time.Second,
+		},
+	})
+	assert.NoError(t, err)
+	natsSource := c.invalidationSources[0].(*NatsSource[string])
+
+	c.handleInvalidation(natsSource.event("keyed", wrapperspb.String("key2"), nil))
+	time.Sleep(100 * time.Millisecond)
+	fullLoads, loadedKeys := source.stats()
+	assert.Equal(t, 1, fullLoads)
+	assert.Equal(t, [][]string{{"key2"}}, loadedKeys)
+
+	// no keys extracted
+	c.handleInvalidation(natsSource.event("keyed", wrapperspb.String(""), nil))
+	time.Sleep(100 * time.Millisecond)
+	fullLoads, _ = source.stats()
+	assert.Equal(t, 2, fullLoads)
+
+	// no extractor
+	c.handleInvalidation(natsSource.event("unkeyed", wrapperspb.String("key2"), nil))
+	time.Sleep(100 * time.Millisecond)
+	fullLoads, loadedKeys = source.stats()
+	assert.Equal(t, 3, fullLoads)
+	assert.Len(t, loadedKeys, 1)
+}
+
+func testInvalidationKeysParams(t *testing.T) {
+	t.Parallel()
+
+	source := newKeyedSource()
+	extractor := func(msg proto.Message) []string { return nil }
+	params := Params[string, int]{
+		Context:     context.Background(),
+		Name:        "testing_cache",
+		LoadAllFunc: source.loadAll,
+		InvalidationKeys: map[string]KeyExtractor[string]{
+			"keyed": extractor,
+		},
+	}
+	assert.Error(t, params.check())
+
+	params.Invalidations = &Invalidations{
+		Nats: test_utils.NatsConnection(t),
+		Messages: map[string]proto.Message{
+			"other": &wrapperspb.StringValue{},
+		},
+	}
+	assert.Error(t, params.check())
+
+	params.Invalidations.Messages["keyed"] = &wrapperspb.StringValue{}
+	assert.NoError(t, params.check())
+}
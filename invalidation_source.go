@@ -0,0 +1,275 @@
+package codebook
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/moderntv/codebook-cache/internal/utils"
+)
+
+// Metadata keys set by built-in invalidation sources.
+const (
+	MetadataSubject = "codebook.subject" // NATS subject of invalidation message
+	MetadataSignal  = "codebook.signal"  // received signal
+	MetadataVersion = "codebook.version" // version returned by version probe
+)
+
+// InvalidationEvent invalidates items with `Keys` or all items when no keys are set.
+type InvalidationEvent[K comparable] struct {
+	Keys []K
+	// Metadata describe the event, e.g. NATS message headers. Span propagated in metadata
+	// becomes parent of the following reload (see `Params.Propagator`).
+	Metadata map[string]string
+}
+
+// InvalidationSource emits invalidation events. Cache starts its sources after the initial load
+// and stops them when it is closed.
+type InvalidationSource[K comparable] interface {
+	// Start starts emitting events by calling `emit` (possibly from other goroutines)
+	// until `ctx` is done or Stop is called.
+	Start(ctx context.Context, emit func(InvalidationEvent[K])) error
+	// Stop stops emitting events.
+	Stop()
+}
+
+// NamedInvalidationSource is invalidation source with name used as `source` label of invalidation
+// metrics. Sources which do not implement it are labeled `custom`.
+type NamedInvalidationSource interface {
+	Name() string
+}
+
+// invalidationSourceName returns name of `source` used in metrics
+func invalidationSourceName(source any) string {
+	named, ok := source.(NamedInvalidationSource)
+	if !ok {
+		return "custom"
+	}
+	return named.Name()
+}
+
+// ChannelSource emits events received from channel. It can be used to invalidate cache from other
+// parts of the application.
+type ChannelSource[K comparable] struct {
+	events <-chan InvalidationEvent[K]
+	stop   chan struct{}
+	once   sync.Once
+}
+
+// NewChannelSource creates invalidation source emitting events received from `events` channel.
+func NewChannelSource[K comparable](events <-chan InvalidationEvent[K]) *ChannelSource[K] {
+	return &ChannelSource[K]{
+		events: events,
+		stop:   make(chan struct{}),
+	}
+}
+
+func (s *ChannelSource[K]) Name() string {
+	return "channel"
+}
+
+func (s *ChannelSource[K]) Start(ctx context.Context, emit func(InvalidationEvent[K])) error {
+	go func() {
+		for {
+			select {
+			case event, ok := <-s.events:
+				if !ok || s.stopped(ctx) {
+					return
+				}
+				emit(event)
+			case <-s.stop:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (s *ChannelSource[K]) Stop() {
+	s.once.Do(func() {
+		close(s.stop)
+	})
+}
+
+// stopped returns true when event received concurrently with stop should be dropped
+func (s *ChannelSource[K]) stopped(ctx context.Context) bool {
+	select {
+	case <-s.stop:
+		return true
+	case <-ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// SignalSource invalidates all items when the process receives a signal.
+type SignalSource[K comparable] struct {
+	signals []os.Signal
+	channel chan os.Signal
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// NewSignalSource creates invalidation source listening for `signals` (SIGHUP when not set).
+func NewSignalSource[K comparable](signals ...os.Signal) *SignalSource[K] {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGHUP}
+	}
+
+	return &SignalSource[K]{
+		signals: signals,
+		channel: make(chan os.Signal, 1),
+		stop:    make(chan struct{}),
+	}
+}
+
+func (s *SignalSource[K]) Name() string {
+	return "signal"
+}
+
+func (s *SignalSource[K]) Start(ctx context.Context, emit func(InvalidationEvent[K])) error {
+	signal.Notify(s.channel, s.signals...)
+
+	go func() {
+		defer signal.Stop(s.channel)
+
+		for {
+			select {
+			case sig := <-s.channel:
+				emit(InvalidationEvent[K]{
+					Metadata: map[string]string{MetadataSignal: sig.String()},
+				})
+			case <-s.stop:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (s *SignalSource[K]) Stop() {
+	s.once.Do(func() {
+		close(s.stop)
+	})
+}
+
+// VersionProbeFunc returns current version of data source. It should be much cheaper than loading
+// all items, e.g. `SELECT max(updated_at) FROM ...`.
+type VersionProbeFunc func(ctx context.Context) (Version, error)
+
+// VersionProbeParams configures `VersionProbeSource`.
+type VersionProbeParams struct {
+	Log   zerolog.Logger
+	Probe VersionProbeFunc
+	// Interval specifies how often is the version probed.
+	Interval time.Duration
+	// Ranomizer randomizes the interval (see `Timeouts.Ranomizer`).
+	Ranomizer float64
+}
+
+func (p *VersionProbeParams) check() error {
+	if p.Probe == nil {
+		return errors.New("Probe must be set")
+	}
+
+	if p.Interval <= 0 {
+		return errors.New("Interval must be positive")
+	}
+
+	if p.Ranomizer < 0 || p.Ranomizer > 1 {
+		return errors.New("Ranomizer must be between 0 and 1")
+	}
+
+	return nil
+}
+
+// VersionProbeSource periodically calls version probe and invalidates all items only when
+// the returned version changes.
+type VersionProbeSource[K comparable] struct {
+	params VersionProbeParams
+	stop   chan struct{}
+	once   sync.Once
+}
+
+// NewVersionProbeSource creates invalidation source polling version of data source.
+func NewVersionProbeSource[K comparable](params VersionProbeParams) (s *VersionProbeSource[K], err error) {
+	err = params.check()
+	if err != nil {
+		return
+	}
+
+	s = &VersionProbeSource[K]{
+		params: params,
+		stop:   make(chan struct{}),
+	}
+	return
+}
+
+func (s *VersionProbeSource[K]) Name() string {
+	return "version_probe"
+}
+
+// Start starts polling. Cache starts its sources after the initial load, so changes between the load
+// and the first probe are unknown - version returned by the first successful probe is always emitted
+// (versioned cache ignores it when it already holds items of this version). Failed probes are logged
+// and retried after `Interval`.
+func (s *VersionProbeSource[K]) Start(ctx context.Context, emit func(InvalidationEvent[K])) error {
+	go func() {
+		var version Version
+		probed := false
+		for delay := time.Duration(0); ; delay = utils.RandomizeDuration(s.params.Interval, s.params.Ranomizer) {
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-s.stop:
+				timer.Stop()
+				return
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+
+			current, err := s.params.Probe(ctx)
+			if err != nil {
+				s.params.Log.Warn().Err(err).Msg("version probe failed")
+				continue
+			}
+			if probed && current == version {
+				continue
+			}
+
+			s.params.Log.Debug().
+				Uint64("version", uint64(current)).
+				Uint64("previous_version", uint64(version)).
+				Bool("first", !probed).
+				Msg("version changed")
+			version = current
+			probed = true
+			emit(InvalidationEvent[K]{
+				Metadata: map[string]string{MetadataVersion: strconv.FormatUint(uint64(current), 10)},
+			})
+		}
+	}()
+
+	return nil
+}
+
+func (s *VersionProbeSource[K]) Stop() {
+	s.once.Do(func() {
+		close(s.stop)
+	})
+}
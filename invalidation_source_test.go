@@ -0,0 +1,237 @@
+package codebook
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moderntv/codebook-cache/internal/test_utils"
+)
+
+func TestInvalidationSource(t *testing.T) {
+	t.Run("testInvalidationSourceChannel", testInvalidationSourceChannel)
+	t.Run("testInvalidationSourceSignal", testInvalidationSourceSignal)
+	t.Run("testInvalidationSourceVersionProbe", testInvalidationSourceVersionProbe)
+	t.Run("testInvalidationSourceVersionProbeBaseline", testInvalidationSourceVersionProbeBaseline)
+	t.Run("testInvalidationSourceStartError", testInvalidationSourceStartError)
+}
+
+func testInvalidationSourceChannel(t *testing.T) {
+	t.Parallel()
+
+	source := newKeyedSource()
+	events := make(chan InvalidationEvent[string])
+	c, err := New(Params[string, int]{
+		Context:             context.Background(),
+		Log:                 test_utils.Logger(),
+		MetricsRegisterer:   prometheus.NewRegistry(),
+		Name:                "testing_cache",
+		LoadAllFunc:         source.loadAll,
+		LoadByKeysFunc:      source.loadByKeys,
+		InvalidationSources: []InvalidationSource[string]{NewChannelSource(events)},
+		Timeouts: Timeouts{
+			ReloadInterval: 5 * time.Second,
+		},
+	})
+	assert.NoError(t, err)
+
+	events <- InvalidationEvent[string]{Keys: []string{"key2"}}
+	time.Sleep(100 * time.Millisecond)
+	fullLoads, loadedKeys := source.stats()
+	assert.Equal(t, 1, fullLoads)
+	assert.Equal(t, [][]string{{"key2"}}, loadedKeys)
+
+	events <- InvalidationEvent[string]{}
+	time.Sleep(100 * time.Millisecond)
+	fullLoads, _ = source.stats()
+	assert.Equal(t, 2, fullLoads)
+
+	// events are counted by source
+	assert.Equal(t, float64(2), testutil.ToFloat64(c.metrics.ReceivedInvalidations.WithLabelValues("channel")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(c.metrics.ReceivedNatsInvalidations))
+
+	// no events are emitted after close
+	assert.NoError(t, c.Close(context.Background()))
+	select {
+	case events <- InvalidationEvent[string]{}:
+	case <-time.After(100 * time.Millisecond):
+	}
+	time.Sleep(100 * time.Millisecond)
+	fullLoads, _ = source.stats()
+	assert.Equal(t, 2, fullLoads)
+}
+
+func testInvalidationSourceSignal(t *testing.T) {
+	var loadCount, loadDelay atomic.Int64
+	var loadErr atomic.Value
+
+	params := countingParams(&loadCount, &loadDelay, &loadErr)
+	params.InvalidationSources = []InvalidationSource[string]{NewSignalSource[string]()}
+	c, err := New(params)
+	assert.NoError(t, err)
+
+	process, err := os.FindProcess(os.Getpid())
+	assert.NoError(t, err)
+	assert.NoError(t, process.Signal(syscall.SIGHUP))
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int64(2), loadCount.Load())
+
+	assert.NoError(t, c.Close(context.Background()))
+}
+
+func testInvalidationSourceVersionProbe(t *testing.T) {
+	t.Parallel()
+
+	var loadCount, loadDelay atomic.Int64
+	var loadErr atomic.Value
+	var version atomic.Uint64
+	var probes, failures atomic.Int64
+
+	failures.Store(2)
+	source, err := NewVersionProbeSource[string](VersionProbeParams{
+		Log: test_utils.Logger(),
+		Probe: func(ctx context.Context) (Version, error) {
+			probes.Add(1)
+			if failures.Add(-1) >= 0 {
+				return 0, errors.New("database is down")
+			}
+			return Version(version.Load()), nil
+		},
+		Interval: 50 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+
+	// failed probes are retried, the first probed version is emitted (change since load is unknown)
+	params := countingParams(&loadCount, &loadDelay, &loadErr)
+	params.InvalidationSources = []InvalidationSource[string]{source}
+	c, err := New(params)
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return loadCount.Load() == 2 }, time.Second, 5*time.Millisecond)
+	assert.Greater(t, probes.Load(), int64(2))
+
+	// unchanged version does not invalidate
+	time.Sleep(180 * time.Millisecond)
+	assert.Equal(t, int64(2), loadCount.Load())
+
+	version.Store(5)
+	assert.Eventually(t, func() bool { return loadCount.Load() == 3 }, time.Second, 5*time.Millisecond)
+
+	assert.NoError(t, c.Close(context.Background()))
+	probesAfterClose := probes.Load()
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, probesAfterClose, probes.Load())
+
+	_, err = NewVersionProbeSource[string](VersionProbeParams{Interval: time.Second})
+	assert.Error(t, err)
+}
+
+func testInvalidationSourceVersionProbeBaseline(t *testing.T) {
+	t.Parallel()
+
+	var version atomic.Uint64
+	var loadCount atomic.Int64
+	// data source changes right after each of the first two loads
+	loadAll := func(ctx context.Context) (map[string]*int, Version, error) {
+		count := loadCount.Add(1)
+		loaded := Version(version.Load())
+		if count <= 2 {
+			version.Add(1)
+		}
+		return map[string]*int{"key1": test_utils.IntPointer(int(loaded))}, loaded, nil
+	}
+
+	source, err := NewVersionProbeSource[string](VersionProbeParams{
+		Log: test_utils.Logger(),
+		Probe: func(ctx context.Context) (Version, error) {
+			return Version(version.Load()), nil
+		},
+		Interval: 50 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+
+	c, err := New(Params[string, int]{
+		Context:              context.Background(),
+		Log:                  test_utils.Logger(),
+		Name:                 "testing_cache",
+		LoadAllVersionedFunc: loadAll,
+		InvalidationSources:  []InvalidationSource[string]{source},
+		Timeouts: Timeouts{
+			ReloadInterval: 10 * time.Second,
+		},
+	})
+	assert.NoError(t, err)
+	defer c.Close(context.Background())
+
+	// change between the initial load and the first probe is not missed
+	assert.Eventually(t, func() bool { return c.Version() == 1 }, time.Second, 5*time.Millisecond)
+	assert.Eventually(t, func() bool { return c.Version() == 2 }, time.Second, 5*time.Millisecond)
+
+	time.Sleep(180 * time.Millisecond)
+	assert.Equal(t, int64(3), loadCount.Load())
+
+	// the first probed version already held by versioned cache does not invalidate
+	stable, err := NewVersionProbeSource[string](VersionProbeParams{
+		Log: test_utils.Logger(),
+		Probe: func(ctx context.Context) (Version, error) {
+			return Version(version.Load()), nil
+		},
+		Interval: 50 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+
+	c2, err := New(Params[string, int]{
+		Context:              context.Background(),
+		Log:                  test_utils.Logger(),
+		Name:                 "testing_cache",
+		LoadAllVersionedFunc: loadAll,
+		InvalidationSources:  []InvalidationSource[string]{stable},
+		Timeouts: Timeouts{
+			ReloadInterval: 10 * time.Second,
+		},
+	})
+	assert.NoError(t, err)
+	defer c2.Close(context.Background())
+
+	time.Sleep(180 * time.Millisecond)
+	assert.Equal(t, int64(4), loadCount.Load())
+	assert.Equal(t, Version(2), c2.Version())
+}
+
+// failingSource cannot be started
+type failingSource struct{}
+
+func (s *failingSource) Start(ctx context.Context, emit func(InvalidationEvent[string])) error {
+	return errors.New("database is down")
+}
+
+func (s *failingSource) Stop() {}
+
+func testInvalidationSourceStartError(t *testing.T) {
+	t.Parallel()
+
+	var loadCount, loadDelay atomic.Int64
+	var loadErr atomic.Value
+
+	source := &failingSource{}
+	events := make(chan InvalidationEvent[string])
+	params := countingParams(&loadCount, &loadDelay, &loadErr)
+	params.InvalidationSources = []InvalidationSource[string]{NewChannelSource(events), source}
+	_, err := New(params)
+	assert.ErrorContains(t, err, "database is down")
+
+	// already started source was stopped
+	select {
+	case events <- InvalidationEvent[string]{}:
+	case <-time.After(100 * time.Millisecond):
+	}
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int64(1), loadCount.Load())
+}
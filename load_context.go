@@ -0,0 +1,84 @@
+package codebook
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// LoadStats describes data source query performed by load function.
+type LoadStats struct {
+	// Rows is number of rows (records) read from data source.
+	Rows int
+	// Duration of the query including reading of all rows.
+	Duration time.Duration
+}
+
+// loadState is passed to load functions in context of one load
+type loadState struct {
+	reportStats func(stats LoadStats)
+
+	mu sync.Mutex
+	// functions called after loaded items are installed
+	installed []func()
+}
+
+type loadStateKey struct{}
+
+// ReportLoadStats reports statistics of data source query into log and metrics of the cache
+// which called load function. `ctx` must be (derived from) the context passed to the load function,
+// nothing is reported otherwise.
+func ReportLoadStats(ctx context.Context, stats LoadStats) {
+	state, ok := ctx.Value(loadStateKey{}).(*loadState)
+	if ok {
+		state.reportStats(stats)
+	}
+}
+
+// OnInstalled registers `fn` called after items returned by load function are installed into the cache
+// (or kept after the load function returned `ErrNotModified`). `fn` is not called when the load fails,
+// its items are rejected or superseded by invalidation. Load functions can use it to commit state
+// of data source (e.g. validators of HTTP response) only when the cache holds items loaded with it.
+// `ctx` must be (derived from) the context passed to the load function, otherwise `fn` is called immediately.
+func OnInstalled(ctx context.Context, fn func()) {
+	state, ok := ctx.Value(loadStateKey{}).(*loadState)
+	if !ok {
+		fn()
+		return
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	state.installed = append(state.installed, fn)
+}
+
+// withLoadState returns context of one load passed to load functions
+func (c *Cache[K, T]) withLoadState(ctx context.Context) (context.Context, *loadState) {
+	state := &loadState{reportStats: c.reportLoadStats}
+	return context.WithValue(ctx, loadStateKey{}, state), state
+}
+
+// notifyInstalled calls functions registered by `OnInstalled` during the load
+func (s *loadState) notifyInstalled() {
+	s.mu.Lock()
+	installed := s.installed
+	s.installed = nil
+	s.mu.Unlock()
+
+	for _, fn := range installed {
+		fn()
+	}
+}
+
+func (c *Cache[K, T]) reportLoadStats(stats LoadStats) {
+	c.log.Debug().
+		Int("rows", stats.Rows).
+		Float64("duration_s", stats.Duration.Round(time.Millisecond).Seconds()).
+		Msg("data source queried")
+
+	if c.metrics != nil {
+		c.metrics.SourceRowCount.Add(float64(stats.Rows))
+		c.metrics.SourceQueryDuration.Observe(stats.Duration.Seconds())
+	}
+}
@@ -0,0 +1,78 @@
+package codebook
+
+import (
+	"context"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/moderntv/codebook-cache/internal/invalidation"
+)
+
+// NatsSource emits invalidation event for each message received on subjects from `Invalidations.Messages`.
+// Keys of invalidated items are extracted by key extractors registered per subject, NATS message headers
+// are passed as event metadata.
+type NatsSource[K comparable] struct {
+	log           zerolog.Logger
+	invalidations *Invalidations
+	keyExtractors map[string]KeyExtractor[K]
+	natsHelper    *invalidation.NatsHelper
+}
+
+// NewNatsSource creates invalidation source receiving NATS messages. `keyExtractors` (by subject,
+// can be nil) extract keys of invalidated items from messages.
+func NewNatsSource[K comparable](
+	log zerolog.Logger,
+	invalidations *Invalidations,
+	keyExtractors map[string]KeyExtractor[K],
+) *NatsSource[K] {
+	return &NatsSource[K]{
+		log:           log,
+		invalidations: invalidations,
+		keyExtractors: keyExtractors,
+	}
+}
+
+func (s *NatsSource[K]) Name() string {
+	return "nats"
+}
+
+// Start subscribes to all subjects. Failed subscriptions are retried in the background.
+func (s *NatsSource[K]) Start(_ context.Context, emit func(InvalidationEvent[K])) error {
+	s.natsHelper = invalidation.NewNatsHelper(s.log, s.invalidations.Nats, s.invalidations.Prefix)
+
+	for subject, message := range s.invalidations.Messages {
+		// subscribe to invalidation message
+		s.natsHelper.Subscribe(subject, message, func(msg proto.Message, header nats.Header) {
+			emit(s.event(subject, msg, header))
+		})
+	}
+
+	return nil
+}
+
+// Stop unsubscribes from all subjects.
+func (s *NatsSource[K]) Stop() {
+	if s.natsHelper != nil {
+		s.natsHelper.Close()
+	}
+}
+
+// event creates invalidation event from received message
+func (s *NatsSource[K]) event(subject string, msg proto.Message, header nats.Header) (event InvalidationEvent[K]) {
+	extractor, exists := s.keyExtractors[subject]
+	if exists {
+		event.Keys = extractor(msg)
+	}
+
+	event.Metadata = make(map[string]string, len(header)+1)
+	for key, values := range header {
+		if len(values) > 0 {
+			event.Metadata[key] = values[0]
+		}
+	}
+	event.Metadata[MetadataSubject] = subject
+
+	return
+}
@@ -3,24 +3,88 @@ package codebook
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	cadre_metrics "github.com/moderntv/cadre/metrics"
 	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/protobuf/proto"
 )
 
 type LoadAllFunc[K comparable, T any] func(ctx context.Context) (entries map[K]*T, err error)
 
+// KeyFunc returns key of loaded item (used by loaders of `fileloader`, `httploader` and `sqlloader` packages).
+type KeyFunc[K comparable, T any] func(item *T) K
+
+// ErrNotModified can be returned by load functions when data source reports that items have not
+// changed since the previous load (e.g. HTTP `304 Not Modified`). The reload is successful,
+// but current items are kept - they are not replaced, validated nor persisted and subscribers
+// are not notified. It is handled as failure when no items have been loaded yet (or they are stale).
+var ErrNotModified = errors.New("not modified")
+
+// LoadAllVersionedFunc loads all items together with version of data source they were loaded at.
+type LoadAllVersionedFunc[K comparable, T any] func(ctx context.Context) (entries map[K]*T, version Version, err error)
+
 type Params[K comparable, T any] struct {
 	Context         context.Context
 	Log             zerolog.Logger
 	MetricsRegistry *cadre_metrics.Registry
-	Invalidations   *Invalidations
-	Name            string
-	LoadAllFunc     LoadAllFunc[K, T]
-	Timeouts        Timeouts
-	MemsizeEnabled  bool
+	// MetricsRegisterer can be used instead of `MetricsRegistry` to register metrics into
+	// plain prometheus registerer.
+	MetricsRegisterer prometheus.Registerer
+	Invalidations     *Invalidations
+	Name              string
+	LoadAllFunc       LoadAllFunc[K, T]
+	// LoadAllVersionedFunc can be used instead of `LoadAllFunc` when data source is versioned.
+	// With `LoadChangesFunc`, the first changes are then loaded since version of all items.
+	LoadAllVersionedFunc LoadAllVersionedFunc[K, T]
+	// LoadAllSeqFunc can be used instead of `LoadAllFunc` to stream items into cache
+	// without building intermediate collection.
+	LoadAllSeqFunc LoadAllSeqFunc[K, T]
+	// LoadChangesFunc enables incremental reloads: periodic reloads and invalidations load only
+	// changes since the current version and apply them on the current items. Full reload is still
+	// performed every `Timeouts.FullReloadInterval` (1 hour by default).
+	LoadChangesFunc LoadChangesFunc[K, T]
+	// LoadByKeysFunc enables reloading only invalidated items (see `Cache.InvalidateKeys`
+	// and `InvalidationKeys`).
+	LoadByKeysFunc LoadByKeysFunc[K, T]
+	// InvalidationSources emit invalidations in addition to NATS messages configured by `Invalidations`
+	// (see `NewChannelSource`, `NewSignalSource` and `NewVersionProbeSource`).
+	InvalidationSources []InvalidationSource[K]
+	// InvalidationKeys extract keys of invalidated items from invalidation messages by subject
+	// (subjects as in `Invalidations.Messages`). When no extractor is set for subject, no keys are
+	// extracted or `LoadByKeysFunc` is not set, invalidation message invalidates all items.
+	InvalidationKeys map[string]KeyExtractor[K]
+	Timeouts         Timeouts
+	// RestartOnInvalidation cancels running load when the cache is invalidated (items being loaded
+	// could miss the invalidated change) and starts it again instead of installing outdated items.
+	RestartOnInvalidation bool
+	// TracerProvider creates span of each reload (no-op provider is used when not set).
+	// Reload caused by invalidation message is a child of span propagated in message headers
+	// (see `InjectSpan`).
+	TracerProvider trace.TracerProvider
+	// Propagator extracts spans from invalidation message headers (W3C trace context when not set).
+	Propagator     propagation.TextMapPropagator
+	MemsizeEnabled bool
+	// Indexes are secondary indexes built together with each loaded set of items.
+	Indexes []Index[K, T]
+	// EqualFunc is used to detect modified items for change subscribers (see `Cache.Subscribe`).
+	// `DefaultEqual` is used when not set.
+	EqualFunc EqualFunc[T]
+	// CloneOnRead makes `Get`, `GetAll`, `Snapshot` and indexes return clones of items, so callers
+	// cannot modify cached items. Items are cloned by `CloneFunc` (`DefaultClone` when not set).
+	CloneOnRead bool
+	CloneFunc   CloneFunc[T]
+	// SnapshotStore persists every successfully loaded set of items. When loading fails
+	// during cache creation, cache is started from the last saved items instead of failing;
+	// such cache is marked as stale and loading is retried in the background.
+	SnapshotStore SnapshotStore[K, T]
+	// Validators check every loaded set of items before it replaces the current one
+	// (see `RejectEmpty` and `RejectShrink`). Rejected items are handled as failed loading.
+	Validators []ValidateFunc[K, T]
 }
 
 func (p *Params[K, T]) check() error {
@@ -32,15 +96,74 @@ func (p *Params[K, T]) check() error {
 		return errors.New("name must be set")
 	}
 
-	if p.LoadAllFunc == nil {
+	if p.MetricsRegistry != nil && p.MetricsRegisterer != nil {
+		return errors.New("only one of MetricsRegistry and MetricsRegisterer can be provided")
+	}
+
+	loadFuncs := 0
+	for _, set := range []bool{p.LoadAllFunc != nil, p.LoadAllVersionedFunc != nil, p.LoadAllSeqFunc != nil} {
+		if set {
+			loadFuncs++
+		}
+	}
+	if loadFuncs == 0 {
 		return errors.New("LoadAllFunc must be provided")
 	}
+	if loadFuncs > 1 {
+		return errors.New("only one of LoadAllFunc, LoadAllVersionedFunc and LoadAllSeqFunc can be provided")
+	}
+
+	if p.CloneFunc != nil && !p.CloneOnRead {
+		return errors.New("CloneFunc requires CloneOnRead")
+	}
 
 	err := p.Timeouts.check()
 	if err != nil {
 		return err
 	}
 
+	for _, validator := range p.Validators {
+		if validator == nil {
+			return errors.New("validator cannot be nil")
+		}
+	}
+
+	indexNames := make(map[string]struct{}, len(p.Indexes))
+	for _, index := range p.Indexes {
+		if index == nil {
+			return errors.New("index cannot be nil")
+		}
+
+		name := index.Name()
+		if name == "" {
+			return errors.New("index name must be set")
+		}
+		if _, exists := indexNames[name]; exists {
+			return fmt.Errorf("duplicate index name %q", name)
+		}
+		indexNames[name] = struct{}{}
+	}
+
+	for _, source := range p.InvalidationSources {
+		if source == nil {
+			return errors.New("invalidation source cannot be nil")
+		}
+	}
+
+	for subject, extractor := range p.InvalidationKeys {
+		if extractor == nil {
+			return fmt.Errorf("key extractor for subject %q cannot be nil", subject)
+		}
+
+		if p.Invalidations == nil {
+			return errors.New("InvalidationKeys require Invalidations")
+		}
+
+		if _, exists := p.Invalidations.Messages[subject]; !exists {
+			return fmt.Errorf("key extractor for unknown subject %q", subject)
+		}
+	}
+
 	if p.Invalidations != nil {
 		return p.Invalidations.check()
 	}
@@ -48,6 +171,39 @@ func (p *Params[K, T]) check() error {
 	return nil
 }
 
+// loadAllVersionedFunc returns function loading all items with version
+// (version is always 0 for `LoadAllFunc`).
+func (p *Params[K, T]) loadAllVersionedFunc() LoadAllVersionedFunc[K, T] {
+	if p.LoadAllVersionedFunc != nil {
+		return p.LoadAllVersionedFunc
+	}
+
+	if p.LoadAllSeqFunc != nil {
+		loadAllSeqFunc := p.LoadAllSeqFunc
+		return func(ctx context.Context) (map[K]*T, Version, error) {
+			entries, err := collectSeq(loadAllSeqFunc(ctx))
+			return entries, 0, err
+		}
+	}
+
+	loadAllFunc := p.LoadAllFunc
+	return func(ctx context.Context) (map[K]*T, Version, error) {
+		entries, err := loadAllFunc(ctx)
+		return entries, 0, err
+	}
+}
+
+// invalidationSources returns all invalidation sources including NATS source created from `Invalidations`
+func (p *Params[K, T]) invalidationSources(log zerolog.Logger) []InvalidationSource[K] {
+	sources := make([]InvalidationSource[K], 0, len(p.InvalidationSources)+1)
+	if p.Invalidations != nil {
+		sources = append(sources, NewNatsSource(log, p.Invalidations, p.InvalidationKeys))
+	}
+
+	return append(sources, p.InvalidationSources...)
+}
+
+// Invalidations configures NATS invalidation messages (see `NatsSource`).
 type Invalidations struct {
 	Nats     *nats.Conn
 	Prefix   string
@@ -0,0 +1,148 @@
+package codebook
+
+import (
+	"slices"
+)
+
+// Entry is an item together with its key.
+type Entry[K comparable, T any] struct {
+	Key  K
+	Item *T
+}
+
+// Entries is a list of items selected from one snapshot. The list is owned by caller; sorting
+// methods sort it in place and return it, so calls can be chained:
+//
+//	page, next := cache.Snapshot().Filter(isActive).SortByKey(cmp.Compare[int]).PageAfter(cursor, cmp.Compare[int], 20)
+type Entries[K comparable, T any] []Entry[K, T]
+
+// Entries returns all items in unspecified order.
+func (s Snapshot[K, T]) Entries() (entries Entries[K, T]) {
+	return s.Filter(func(K, *T) bool { return true })
+}
+
+// Filter returns all items matching `predicate` in unspecified order.
+// Predicate receives shared items (only returned items are cloned), it must not modify them.
+func (s Snapshot[K, T]) Filter(predicate func(key K, item *T) bool) (entries Entries[K, T]) {
+	entries = make(Entries[K, T], 0)
+	for key, item := range s.data.entries {
+		if predicate(key, item) {
+			entries = append(entries, Entry[K, T]{Key: key, Item: s.item(item)})
+		}
+	}
+
+	return
+}
+
+// Find returns any item matching `predicate` (`found` is false when no item matches). Use `Filter`
+// and sort the result when the first matching item in some order is needed.
+// Predicate receives shared items (only returned item is cloned), it must not modify them.
+func (s Snapshot[K, T]) Find(predicate func(key K, item *T) bool) (key K, item *T, found bool) {
+	for key, item := range s.data.entries {
+		if predicate(key, item) {
+			return key, s.item(item), true
+		}
+	}
+
+	return
+}
+
+// GroupBy groups all items of snapshot by key returned by `groupFunc`. Order of items in groups
+// is not defined. `groupFunc` receives shared items (grouped items are cloned), it must not modify them.
+func GroupBy[K comparable, T any, G comparable](s Snapshot[K, T], groupFunc func(key K, item *T) G) (groups map[G][]*T) {
+	groups = make(map[G][]*T)
+	for key, item := range s.data.entries {
+		group := groupFunc(key, item)
+		groups[group] = append(groups[group], s.item(item))
+	}
+
+	return
+}
+
+// Filter returns new list of entries matching `predicate` in the original order.
+func (e Entries[K, T]) Filter(predicate func(key K, item *T) bool) (filtered Entries[K, T]) {
+	filtered = make(Entries[K, T], 0)
+	for _, entry := range e {
+		if predicate(entry.Key, entry.Item) {
+			filtered = append(filtered, entry)
+		}
+	}
+
+	return
+}
+
+// SortByKey sorts entries by keys using `compare` (e.g. `cmp.Compare[K]`).
+func (e Entries[K, T]) SortByKey(compare func(a, b K) int) Entries[K, T] {
+	slices.SortStableFunc(e, func(a, b Entry[K, T]) int {
+		return compare(a.Key, b.Key)
+	})
+
+	return e
+}
+
+// SortBy sorts entries by items using `compare`. Sorting is stable, so equal items keep their
+// order; sort by key first to get deterministic order of equal items.
+func (e Entries[K, T]) SortBy(compare func(a, b *T) int) Entries[K, T] {
+	slices.SortStableFunc(e, func(a, b Entry[K, T]) int {
+		return compare(a.Item, b.Item)
+	})
+
+	return e
+}
+
+// Page returns at most `limit` entries starting at `offset` (all remaining entries when `limit`
+// is 0). Returned list shares entries with `e`.
+func (e Entries[K, T]) Page(offset, limit int) Entries[K, T] {
+	offset = min(max(offset, 0), len(e))
+	end := len(e)
+	if limit > 0 {
+		end = min(offset+limit, end)
+	}
+
+	return e[offset:end]
+}
+
+// PageAfter returns at most `limit` entries with keys following key `after` (from the first
+// entry when `after` is nil) and cursor of the next page (nil when there are no more entries).
+// Entries must be sorted by keys using `compare` (see `SortByKey`); the page is found by binary
+// search, so the cursor stays valid even when its item has been deleted meanwhile.
+func (e Entries[K, T]) PageAfter(after *K, compare func(a, b K) int, limit int) (page Entries[K, T], next *K) {
+	offset := 0
+	if after != nil {
+		var found bool
+		offset, found = slices.BinarySearchFunc(e, *after, func(entry Entry[K, T], key K) int {
+			return compare(entry.Key, key)
+		})
+		if found {
+			offset++
+		}
+	}
+
+	page = e.Page(offset, limit)
+	if offset+len(page) < len(e) && len(page) > 0 {
+		key := page[len(page)-1].Key
+		next = &key
+	}
+
+	return
+}
+
+// Keys returns keys of entries.
+func (e Entries[K, T]) Keys() (keys []K) {
+	keys = make([]K, len(e))
+	for i, entry := range e {
+		keys[i] = entry.Key
+	}
+
+	return
+}
+
+// Items returns items of entries.
+func (e Entries[K, T]) Items() (items []*T) {
+	items = make([]*T, len(e))
+	for i, entry := range e {
+		items[i] = entry.Item
+	}
+
+	return
+}
@@ -0,0 +1,141 @@
+package codebook
+
+import (
+	"cmp"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuery(t *testing.T) {
+	t.Run("testQueryFilter", testQueryFilter)
+	t.Run("testQueryGroupBy", testQueryGroupBy)
+	t.Run("testQuerySort", testQuerySort)
+	t.Run("testQueryPage", testQueryPage)
+}
+
+func queryCache(t *testing.T) *Cache[int, testChannel] {
+	c, err := New(channelsParams(func(ctx context.Context) (map[int]*testChannel, error) {
+		return map[int]*testChannel{
+			1: {ID: 1, Slug: "news", Package: "basic"},
+			2: {ID: 2, Slug: "sport", Package: "premium"},
+			3: {ID: 3, Slug: "movies", Package: "premium"},
+			4: {ID: 4, Slug: "kids", Package: "basic"},
+			5: {ID: 5, Slug: "music", Package: "basic"},
+		}, nil
+	}))
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		c.Close(context.Background())
+	})
+
+	return c
+}
+
+func byPackage(pkg string) func(int, *testChannel) bool {
+	return func(_ int, ch *testChannel) bool {
+		return ch.Package == pkg
+	}
+}
+
+func testQueryFilter(t *testing.T) {
+	t.Parallel()
+
+	s := queryCache(t).Snapshot()
+
+	assert.Len(t, s.Entries(), 5)
+	premium := s.Filter(byPackage("premium"))
+	assert.ElementsMatch(t, []int{2, 3}, premium.Keys())
+	assert.Empty(t, s.Filter(byPackage("none")))
+
+	startsWithM := func(_ int, ch *testChannel) bool { return strings.HasPrefix(ch.Slug, "m") }
+	assert.Equal(t, []int{3}, premium.Filter(startsWithM).Keys())
+
+	key, item, found := s.Find(func(_ int, ch *testChannel) bool { return ch.Slug == "kids" })
+	assert.True(t, found)
+	assert.Equal(t, 4, key)
+	assert.Same(t, s.Get(4), item)
+
+	_, item, found = s.Find(byPackage("none"))
+	assert.False(t, found)
+	assert.Nil(t, item)
+}
+
+func testQueryGroupBy(t *testing.T) {
+	t.Parallel()
+
+	s := queryCache(t).Snapshot()
+
+	groups := GroupBy(s, func(_ int, ch *testChannel) string { return ch.Package })
+	assert.Len(t, groups, 2)
+	assert.ElementsMatch(t, []*testChannel{s.Get(1), s.Get(4), s.Get(5)}, groups["basic"])
+	assert.ElementsMatch(t, []*testChannel{s.Get(2), s.Get(3)}, groups["premium"])
+
+	bySlugLength := GroupBy(s, func(_ int, ch *testChannel) int { return len(ch.Slug) })
+	assert.Len(t, bySlugLength[4], 2)
+	assert.Len(t, bySlugLength[6], 1)
+}
+
+func testQuerySort(t *testing.T) {
+	t.Parallel()
+
+	s := queryCache(t).Snapshot()
+
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, s.Entries().SortByKey(cmp.Compare[int]).Keys())
+	assert.Equal(t, []int{4, 3, 5, 1, 2}, s.Entries().SortBy(func(a, b *testChannel) int {
+		return cmp.Compare(a.Slug, b.Slug)
+	}).Keys())
+
+	// equal items keep order of keys
+	byPkg := func(a, b *testChannel) int { return cmp.Compare(a.Package, b.Package) }
+	assert.Equal(t, []int{1, 4, 5, 2, 3}, s.Entries().SortByKey(cmp.Compare[int]).SortBy(byPkg).Keys())
+	reversed := func(a, b int) int { return b - a }
+	assert.Equal(t, []int{5, 4, 1, 3, 2}, s.Entries().SortByKey(reversed).SortBy(byPkg).Keys())
+
+	items := s.Filter(byPackage("premium")).SortByKey(cmp.Compare[int]).Items()
+	assert.Equal(t, []*testChannel{s.Get(2), s.Get(3)}, items)
+}
+
+func testQueryPage(t *testing.T) {
+	t.Parallel()
+
+	entries := queryCache(t).Snapshot().Entries().SortByKey(cmp.Compare[int])
+
+	assert.Equal(t, []int{1, 2}, entries.Page(0, 2).Keys())
+	assert.Equal(t, []int{5}, entries.Page(4, 2).Keys())
+	assert.Empty(t, entries.Page(10, 2))
+	assert.Equal(t, []int{3, 4, 5}, entries.Page(2, 0).Keys())
+	assert.Equal(t, []int{1}, entries.Page(-1, 1).Keys())
+
+	// cursor pagination
+	var pages [][]int
+	var cursor *int
+	for {
+		page, next := entries.PageAfter(cursor, cmp.Compare[int], 2)
+		pages = append(pages, page.Keys())
+		if next == nil {
+			break
+		}
+		cursor = next
+	}
+	assert.Equal(t, [][]int{{1, 2}, {3, 4}, {5}}, pages)
+
+	// the last page is not followed by an empty one
+	four := 4
+	page, next := entries.PageAfter(&four, cmp.Compare[int], 1)
+	assert.Equal(t, []int{5}, page.Keys())
+	assert.Nil(t, next)
+
+	// cursor item deleted by reload
+	two := 2
+	page, next = entries.Filter(func(key int, _ *testChannel) bool { return key != two }).PageAfter(&two, cmp.Compare[int], 2)
+	assert.Equal(t, []int{3, 4}, page.Keys())
+	assert.Equal(t, &four, next)
+
+	missing := 10
+	page, next = entries.PageAfter(&missing, cmp.Compare[int], 2)
+	assert.Empty(t, page)
+	assert.Nil(t, next)
+}
@@ -0,0 +1,75 @@
+package codebook
+
+import (
+	"context"
+	"iter"
+
+	"github.com/moderntv/codebook-cache/internal/utils"
+)
+
+// LoadAllSeqFunc returns iterator over all items (e.g. rows read from database one by one)
+// and function returning error of the iteration, which is called after the iteration finishes.
+// Items are inserted into new map directly, so no intermediate collection of items is needed.
+type LoadAllSeqFunc[K comparable, T any] func(ctx context.Context) (items iter.Seq2[K, *T], errFunc func() error)
+
+// collectSeq inserts items from iterator into new map. Duplicate keys are reported as error.
+func collectSeq[K comparable, T any](items iter.Seq2[K, *T], errFunc func() error) (entries map[K]*T, err error) {
+	entries = make(map[K]*T)
+	for key, item := range items {
+		err = utils.AddEntry(entries, key, item)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if errFunc != nil {
+		err = errFunc()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// All returns iterator over all items of one snapshot (see `Snapshot`) in unspecified order.
+func (c *Cache[K, T]) All() iter.Seq2[K, *T] {
+	return c.Snapshot().All()
+}
+
+// Keys returns iterator over keys of all items of one snapshot in unspecified order.
+func (c *Cache[K, T]) Keys() iter.Seq[K] {
+	return c.Snapshot().Keys()
+}
+
+// Values returns iterator over all items of one snapshot in unspecified order.
+func (c *Cache[K, T]) Values() iter.Seq[*T] {
+	return c.Snapshot().Values()
+}
+
+// All returns iterator over all items in unspecified order.
+func (s Snapshot[K, T]) All() iter.Seq2[K, *T] {
+	return func(yield func(K, *T) bool) {
+		s.Range(yield)
+	}
+}
+
+// Keys returns iterator over keys of all items in unspecified order (see `KeyList` for a slice).
+func (s Snapshot[K, T]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for key := range s.data.entries {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns iterator over all items in unspecified order.
+func (s Snapshot[K, T]) Values() iter.Seq[*T] {
+	return func(yield func(*T) bool) {
+		s.Range(func(_ K, item *T) bool {
+			return yield(item)
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package codebook
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeq(t *testing.T) {
+	t.Run("testSeqIterators", testSeqIterators)
+	t.Run("testSeqConsistent", testSeqConsistent)
+	t.Run("testSeqLoader", testSeqLoader)
+}
+
+func testSeqIterators(t *testing.T) {
+	t.Parallel()
+
+	c := queryCache(t)
+
+	assert.Equal(t, c.GetAll(), maps.Collect(c.All()))
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, slices.Sorted(c.Keys()))
+	assert.ElementsMatch(t, c.Snapshot().Entries().Items(), slices.Collect(c.Values()))
+	assert.Equal(t, c.GetAll(), maps.Collect(c.Snapshot().All()))
+
+	// iteration can be stopped
+	count := 0
+	for range c.All() {
+		count++
+		break
+	}
+	for range c.Keys() {
+		count++
+		break
+	}
+	for range c.Values() {
+		count++
+		break
+	}
+	assert.Equal(t, 3, count)
+}
+
+func testSeqConsistent(t *testing.T) {
+	t.Parallel()
+
+	generation := 0
+	c, err := New(channelsParams(func(ctx context.Context) (map[int]*testChannel, error) {
+		generation++
+		entries := map[int]*testChannel{}
+		for i := 1; i <= generation; i++ {
+			entries[i] = &testChannel{ID: i}
+		}
+		return entries, nil
+	}))
+	assert.NoError(t, err)
+	defer c.Close(context.Background())
+
+	// iterators read items of the snapshot taken when they were created
+	all, keys, values := c.All(), c.Keys(), c.Values()
+	assert.NoError(t, c.InvalidateAndWait(context.Background()))
+
+	assert.Len(t, maps.Collect(all), 1)
+	assert.Len(t, slices.Collect(keys), 1)
+	assert.Len(t, slices.Collect(values), 1)
+	assert.Len(t, maps.Collect(c.All()), 2)
+}
+
+func testSeqLoader(t *testing.T) {
+	t.Parallel()
+
+	var loadErr error
+	var keys []int
+	params := channelsParams(nil)
+	params.LoadAllSeqFunc = func(ctx context.Context) (iter.Seq2[int, *testChannel], func() error) {
+		var iterErr error
+		items := func(yield func(int, *testChannel) bool) {
+			for _, key := range keys {
+				if !yield(key, &testChannel{ID: key}) {
+					return
+				}
+			}
+			iterErr = loadErr
+		}
+
+		return items, func() error { return iterErr }
+	}
+
+	keys = []int{1, 2, 3}
+	c, err := New(params)
+	assert.NoError(t, err)
+	defer c.Close(context.Background())
+	assert.Equal(t, []int{1, 2, 3}, slices.Sorted(c.Keys()))
+	assert.Equal(t, 2, c.Get(2).ID)
+
+	// iteration error fails the load
+	keys = []int{1, 2}
+	loadErr = errors.New("connection reset")
+	assert.EqualError(t, c.InvalidateAndWait(context.Background()), "connection reset")
+	assert.Equal(t, []int{1, 2, 3}, slices.Sorted(c.Keys()))
+
+	loadErr = nil
+	keys = []int{1, 2, 1}
+	assert.EqualError(t, c.InvalidateAndWait(context.Background()), "duplicate key 1")
+
+	keys = []int{4}
+	assert.NoError(t, c.InvalidateAndWait(context.Background()))
+	assert.Equal(t, []int{4}, slices.Sorted(c.Keys()))
+
+	params.LoadAllFunc = func(ctx context.Context) (map[int]*testChannel, error) {
+		return nil, nil
+	}
+	_, err = New(params)
+	assert.EqualError(t, err, "only one of LoadAllFunc, LoadAllVersionedFunc and LoadAllSeqFunc can be provided")
+}
@@ -0,0 +1,96 @@
+package codebook
+
+import (
+	"slices"
+)
+
+// Snapshot is a read-only view of one consistent set of items loaded into cache. It is not
+// affected by later reloads, so all reads from one snapshot see the same generation of items.
+// With `Params.CloneOnRead`, every returned item is a clone.
+type Snapshot[K comparable, T any] struct {
+	data  *dataset[K, T]
+	clone CloneFunc[T]
+}
+
+// Snapshot returns read-only view of currently loaded items.
+func (c *Cache[K, T]) Snapshot() Snapshot[K, T] {
+	return Snapshot[K, T]{
+		data:  c.loadData(),
+		clone: c.cloneFunc,
+	}
+}
+
+// Get returns item with given key or `nil` when no such item exists.
+func (s Snapshot[K, T]) Get(key K) *T {
+	item, exists := s.data.entries[key]
+	if !exists {
+		return nil
+	}
+
+	return s.item(item)
+}
+
+// Len returns number of items.
+func (s Snapshot[K, T]) Len() int {
+	return len(s.data.entries)
+}
+
+// Version returns data source version the items were loaded at (see `Cache.Version`).
+func (s Snapshot[K, T]) Version() Version {
+	return s.data.version
+}
+
+// Range calls `fn` for each item in unspecified order until `fn` returns false.
+func (s Snapshot[K, T]) Range(fn func(key K, item *T) bool) {
+	for key, item := range s.data.entries {
+		if !fn(key, s.item(item)) {
+			return
+		}
+	}
+}
+
+// KeyList returns keys of all items in unspecified order.
+func (s Snapshot[K, T]) KeyList() (keys []K) {
+	keys = make([]K, 0, len(s.data.entries))
+	for key := range s.data.entries {
+		keys = append(keys, key)
+	}
+
+	return
+}
+
+// SortedKeys returns keys of all items sorted by `compare` (e.g. `cmp.Compare[K]`).
+func (s Snapshot[K, T]) SortedKeys(compare func(a, b K) int) (keys []K) {
+	keys = s.KeyList()
+	slices.SortFunc(keys, compare)
+	return
+}
+
+// RangeSorted calls `fn` for each item in order of keys sorted by `compare` until `fn` returns false.
+func (s Snapshot[K, T]) RangeSorted(compare func(a, b K) int, fn func(key K, item *T) bool) {
+	for _, key := range s.SortedKeys(compare) {
+		if !fn(key, s.item(s.data.entries[key])) {
+			return
+		}
+	}
+}
+
+// ToMap returns copy of all items which can be modified by caller (items themselves are shared
+// unless they are cloned on read).
+func (s Snapshot[K, T]) ToMap() (entries map[K]*T) {
+	entries = make(map[K]*T, len(s.data.entries))
+	for key, item := range s.data.entries {
+		entries[key] = s.item(item)
+	}
+
+	return
+}
+
+// item returns clone of item when cloning on read is enabled
+func (s Snapshot[K, T]) item(item *T) *T {
+	if s.clone == nil {
+		return item
+	}
+
+	return s.clone(item)
+}
@@ -0,0 +1,186 @@
+package codebook
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// staleRetryInterval specifies how often is loading retried while cache serves stale data
+// restored from snapshot store.
+const staleRetryInterval = 10 * time.Second
+
+// SnapshotStore persists the last successfully loaded set of items, so the cache can be created
+// even when data source is not available (see `Params.SnapshotStore`).
+type SnapshotStore[K comparable, T any] interface {
+	// Save stores all items loaded at `version`, replacing previously saved ones.
+	Save(entries map[K]*T, version Version) error
+	// Load returns the last saved items together with their version and time when they were saved.
+	Load() (entries map[K]*T, version Version, savedAt time.Time, err error)
+}
+
+// FileSnapshotStore stores items in one file encoded by given codec. The file starts with
+// version of items (8 bytes, big endian) followed by encoded items.
+type FileSnapshotStore[K comparable, T any] struct {
+	path  string
+	codec Codec[K, T]
+}
+
+// NewFileSnapshotStore creates snapshot store writing into file `path` using `codec`.
+func NewFileSnapshotStore[K comparable, T any](path string, codec Codec[K, T]) *FileSnapshotStore[K, T] {
+	return &FileSnapshotStore[K, T]{
+		path:  path,
+		codec: codec,
+	}
+}
+
+// Save writes items into temporary file which then atomically replaces the snapshot file,
+// so the snapshot file is never partially written. The file is synced to disk before replacing.
+func (s *FileSnapshotStore[K, T]) Save(entries map[K]*T, version Version) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp*")
+	if err != nil {
+		err = fmt.Errorf("cannot create temporary file: %w", err)
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(f.Name()) // ignore error
+		}
+	}()
+
+	_, err = f.Write(binary.BigEndian.AppendUint64(nil, uint64(version)))
+	if err != nil {
+		_ = f.Close()
+		err = fmt.Errorf("cannot write version: %w", err)
+		return
+	}
+
+	err = s.codec.Encode(f, entries)
+	if err != nil {
+		_ = f.Close()
+		err = fmt.Errorf("cannot encode entries: %w", err)
+		return
+	}
+
+	// renamed file must not be empty after crash
+	err = f.Sync()
+	if err != nil {
+		_ = f.Close()
+		err = fmt.Errorf("cannot sync temporary file: %w", err)
+		return
+	}
+
+	err = f.Close()
+	if err != nil {
+		err = fmt.Errorf("cannot write temporary file: %w", err)
+		return
+	}
+
+	err = os.Rename(f.Name(), s.path)
+	if err != nil {
+		err = fmt.Errorf("cannot replace snapshot file: %w", err)
+		return
+	}
+
+	return
+}
+
+func (s *FileSnapshotStore[K, T]) Load() (entries map[K]*T, version Version, savedAt time.Time, err error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return
+	}
+
+	header := make([]byte, 8)
+	_, err = io.ReadFull(f, header)
+	if err != nil {
+		err = fmt.Errorf("cannot read version: %w", err)
+		return
+	}
+	version = Version(binary.BigEndian.Uint64(header))
+
+	entries, err = s.codec.Decode(f)
+	if err != nil {
+		err = fmt.Errorf("cannot decode entries: %w", err)
+		return
+	}
+
+	savedAt = info.ModTime()
+	return
+}
+
+// restoreSnapshot fills cache with data from snapshot store after failed loading.
+// Returns `loadErr` extended by restoring error when restoring fails.
+func (c *Cache[K, T]) restoreSnapshot(loadErr error) (err error) {
+	entries, version, savedAt, err := c.snapshotStore.Load()
+	if err != nil {
+		err = fmt.Errorf("%w (cannot restore snapshot: %v)", loadErr, err)
+		return
+	}
+
+	data, err := newDataset(entries, version, c.indexes)
+	if err != nil {
+		err = fmt.Errorf("%w (cannot restore snapshot: %v)", loadErr, err)
+		return
+	}
+
+	c.data.Store(data)
+	c.stale.Store(true)
+
+	nextReloadTime := time.Now().Add(staleRetryInterval)
+	c.mu.Lock()
+	c.nextReload = &nextReloadTime
+	c.mu.Unlock()
+
+	c.log.Warn().
+		Err(loadErr).
+		Time("saved_at", savedAt).
+		Uint64("version", uint64(version)).
+		Int("count", len(entries)).
+		Msg("loading failed, cache started from snapshot")
+
+	if c.metrics != nil {
+		c.metrics.ItemsCount.Set(float64(len(entries)))
+	}
+	if c.memSizeEnabled {
+		go c.updateMemSize()
+	}
+
+	return
+}
+
+// saveSnapshot writes current data into snapshot store.
+func (c *Cache[K, T]) saveSnapshot() {
+	defer c.persistWg.Done()
+
+	c.persistMu.Lock()
+	defer c.persistMu.Unlock()
+
+	// always write the newest data (older writes may be still waiting)
+	data := c.loadData()
+	if c.stale.Load() {
+		return
+	}
+
+	start := time.Now()
+	err := c.snapshotStore.Save(data.entries, data.version)
+	if err != nil {
+		c.log.Warn().
+			Err(err).
+			Msg("cannot save snapshot")
+		return
+	}
+
+	c.log.Trace().
+		Float64("duration_s", time.Since(start).Round(time.Millisecond).Seconds()).
+		Msg("snapshot saved")
+}
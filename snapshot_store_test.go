@@ -0,0 +1,159 @@
+package codebook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/moderntv/codebook-cache/internal/test_utils"
+)
+
+func TestSnapshotStore(t *testing.T) {
+	t.Run("testSnapshotStoreCodecs", testSnapshotStoreCodecs)
+	t.Run("testSnapshotStoreProtoCodec", testSnapshotStoreProtoCodec)
+	t.Run("testSnapshotStoreFile", testSnapshotStoreFile)
+	t.Run("testSnapshotStoreWarmStart", testSnapshotStoreWarmStart)
+}
+
+func testSnapshotStoreCodecs(t *testing.T) {
+	t.Parallel()
+
+	entries := map[int]*testChannel{
+		1: {ID: 1, Slug: "one", Package: "basic"},
+		2: {ID: 2, Slug: "two", Package: "premium"},
+	}
+
+	for name, codec := range map[string]Codec[int, testChannel]{
+		"gob":  NewGobCodec[int, testChannel](),
+		"json": NewJSONCodec[int, testChannel](),
+	} {
+		buf := bytes.Buffer{}
+		assert.NoError(t, codec.Encode(&buf, entries), name)
+
+		decoded, err := codec.Decode(&buf)
+		assert.NoError(t, err, name)
+		assert.Equal(t, entries, decoded, name)
+	}
+}
+
+func testSnapshotStoreProtoCodec(t *testing.T) {
+	t.Parallel()
+
+	entries := map[string]*wrapperspb.StringValue{
+		"one": wrapperspb.String("one"),
+		"two": wrapperspb.String("two"),
+	}
+
+	codec := NewProtoCodec(func(entry *wrapperspb.StringValue) string { return entry.GetValue() })
+	buf := bytes.Buffer{}
+	assert.NoError(t, codec.Encode(&buf, entries))
+
+	decoded, err := codec.Decode(&buf)
+	assert.NoError(t, err)
+	assert.Len(t, decoded, len(entries))
+	for key, entry := range entries {
+		assert.True(t, proto.Equal(entry, decoded[key]), key)
+	}
+
+	// truncated data
+	buf.Reset()
+	assert.NoError(t, codec.Encode(&buf, entries))
+	_, err = codec.Decode(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	assert.Error(t, err)
+
+	// corrupted sizes
+	_, err = codec.Decode(bytes.NewReader(protowire.AppendVarint(nil, 1<<62)))
+	assert.Error(t, err)
+	_, err = codec.Decode(bytes.NewReader(protowire.AppendVarint(nil, 1<<30)))
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func testSnapshotStoreFile(t *testing.T) {
+	t.Parallel()
+
+	store := NewFileSnapshotStore(filepath.Join(t.TempDir(), "snapshot.json"), NewJSONCodec[int, testChannel]())
+
+	_, _, _, err := store.Load()
+	assert.Error(t, err)
+
+	entries := map[int]*testChannel{
+		1: {ID: 1, Slug: "one"},
+	}
+	assert.NoError(t, store.Save(entries, 42))
+
+	loaded, version, savedAt, err := store.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, entries, loaded)
+	assert.Equal(t, Version(42), version)
+	assert.WithinDuration(t, time.Now(), savedAt, 5*time.Second)
+
+	// saving into not existing directory fails
+	store = NewFileSnapshotStore(filepath.Join(t.TempDir(), "missing", "snapshot.json"), NewJSONCodec[int, testChannel]())
+	assert.Error(t, store.Save(entries, 42))
+}
+
+func testSnapshotStoreWarmStart(t *testing.T) {
+	t.Parallel()
+
+	var failing atomic.Bool
+	store := NewFileSnapshotStore(filepath.Join(t.TempDir(), "snapshot.gob"), NewGobCodec[int, testChannel]())
+	bySlug := NewUniqueIndex[int]("slug", func(ch *testChannel) string { return ch.Slug })
+
+	params := Params[int, testChannel]{
+		Context: context.Background(),
+		Log:     test_utils.Logger(),
+		Name:    "testing_cache",
+		LoadAllVersionedFunc: func(ctx context.Context) (map[int]*testChannel, Version, error) {
+			if failing.Load() {
+				return nil, 0, errors.New("database is down")
+			}
+
+			return map[int]*testChannel{
+				1: {ID: 1, Slug: "one"},
+				2: {ID: 2, Slug: "two"},
+			}, 7, nil
+		},
+		Indexes:       []Index[int, testChannel]{bySlug},
+		SnapshotStore: store,
+		Timeouts: Timeouts{
+			ReloadInterval: 5 * time.Second,
+		},
+	}
+
+	// no snapshot saved yet
+	failing.Store(true)
+	_, err := New(params)
+	assert.Error(t, err)
+
+	// successful load saves snapshot
+	failing.Store(false)
+	c, err := New(params)
+	assert.NoError(t, err)
+	assert.False(t, c.IsStale())
+	assert.NoError(t, c.Close(context.Background()))
+
+	// cache is started from snapshot
+	failing.Store(true)
+	c, err = New(params)
+	assert.NoError(t, err)
+	assert.True(t, c.IsStale())
+	assert.Equal(t, Version(7), c.Version())
+	assert.Len(t, c.GetAll(), 2)
+	assert.Equal(t, 2, bySlug.Get(c, "two").ID)
+
+	// successful reload clears stale flag
+	failing.Store(false)
+	c.InvalidateAll()
+	assert.Eventually(t, func() bool { return !c.IsStale() }, 5*time.Second, 10*time.Millisecond)
+	assert.NoError(t, c.Close(context.Background()))
+}
@@ -0,0 +1,206 @@
+package codebook
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/moderntv/codebook-cache/internal/test_utils"
+)
+
+type testProgram struct {
+	ID       int
+	Title    *string
+	Tags     []string
+	Labels   map[string]*int
+	Channels [2]*testChannel
+	Extra    any
+	Name     *wrapperspb.StringValue
+	private  *int
+}
+
+func TestSnapshot(t *testing.T) {
+	t.Run("testSnapshotRead", testSnapshotRead)
+	t.Run("testSnapshotConsistent", testSnapshotConsistent)
+	t.Run("testSnapshotCloneOnRead", testSnapshotCloneOnRead)
+	t.Run("testSnapshotDefaultClone", testSnapshotDefaultClone)
+}
+
+func channelsParams(loadAllFunc LoadAllFunc[int, testChannel]) Params[int, testChannel] {
+	return Params[int, testChannel]{
+		Context:     context.Background(),
+		Log:         test_utils.Logger(),
+		Name:        "testing_cache",
+		LoadAllFunc: loadAllFunc,
+		Timeouts: Timeouts{
+			ReloadInterval: 10 * time.Second,
+		},
+	}
+}
+
+func testSnapshotRead(t *testing.T) {
+	t.Parallel()
+
+	c, err := New(channelsParams(func(ctx context.Context) (map[int]*testChannel, error) {
+		return map[int]*testChannel{
+			3: {ID: 3, Slug: "three"},
+			1: {ID: 1, Slug: "one"},
+			2: {ID: 2, Slug: "two"},
+		}, nil
+	}))
+	assert.NoError(t, err)
+	defer c.Close(context.Background())
+
+	s := c.Snapshot()
+	assert.Equal(t, 3, s.Len())
+	assert.Equal(t, "two", s.Get(2).Slug)
+	assert.Nil(t, s.Get(4))
+	assert.Same(t, c.Get(2), s.Get(2))
+	assert.ElementsMatch(t, []int{1, 2, 3}, s.KeyList())
+	assert.ElementsMatch(t, []int{1, 2, 3}, slices.Collect(s.Keys()))
+	assert.Equal(t, []int{1, 2, 3}, s.SortedKeys(cmp.Compare[int]))
+
+	keys := []int{}
+	s.RangeSorted(func(a, b int) int { return b - a }, func(key int, item *testChannel) bool {
+		keys = append(keys, key)
+		assert.Equal(t, key, item.ID)
+		return key > 2
+	})
+	assert.Equal(t, []int{3, 2}, keys)
+
+	count := 0
+	s.Range(func(key int, item *testChannel) bool {
+		count++
+		return false
+	})
+	assert.Equal(t, 1, count)
+
+	// copy can be modified
+	entries := s.ToMap()
+	delete(entries, 1)
+	assert.Equal(t, 3, s.Len())
+	assert.Len(t, c.GetAll(), 3)
+}
+
+func testSnapshotConsistent(t *testing.T) {
+	t.Parallel()
+
+	generation := 0
+	c, err := New(channelsParams(func(ctx context.Context) (map[int]*testChannel, error) {
+		generation++
+		entries := map[int]*testChannel{}
+		for i := 1; i <= generation; i++ {
+			entries[i] = &testChannel{ID: i, Package: "generation " + strconv.Itoa(generation)}
+		}
+		return entries, nil
+	}))
+	assert.NoError(t, err)
+	defer c.Close(context.Background())
+
+	s := c.Snapshot()
+	assert.NoError(t, c.InvalidateAndWait(context.Background()))
+
+	// reload does not affect existing snapshot
+	assert.Equal(t, 1, s.Len())
+	assert.Equal(t, "generation 1", s.Get(1).Package)
+	assert.Nil(t, s.Get(2))
+	assert.Equal(t, 2, c.Snapshot().Len())
+	assert.Equal(t, "generation 2", c.Snapshot().Get(1).Package)
+}
+
+func testSnapshotCloneOnRead(t *testing.T) {
+	t.Parallel()
+
+	byPackage := NewMultiIndex[int]("package", func(ch *testChannel) string { return ch.Package })
+	bySlug := NewUniqueIndex[int]("slug", func(ch *testChannel) string { return ch.Slug })
+	params := channelsParams(func(ctx context.Context) (map[int]*testChannel, error) {
+		return map[int]*testChannel{
+			1: {ID: 1, Slug: "one", Package: "basic"},
+			2: {ID: 2, Slug: "two", Package: "basic"},
+		}, nil
+	})
+	params.Indexes = []Index[int, testChannel]{byPackage, bySlug}
+	var clones atomic.Int64
+	params.CloneFunc = func(item *testChannel) *testChannel {
+		clones.Add(1)
+		clone := *item
+		return &clone
+	}
+
+	_, err := New(params)
+	assert.EqualError(t, err, "CloneFunc requires CloneOnRead")
+
+	params.CloneOnRead = true
+	c, err := New(params)
+	assert.NoError(t, err)
+	defer c.Close(context.Background())
+
+	// callers cannot modify cached items
+	c.Get(1).Slug = "modified"
+	c.GetAll()[1].Slug = "modified"
+	c.Snapshot().Get(1).Slug = "modified"
+	c.Snapshot().ToMap()[1].Slug = "modified"
+	bySlug.Get(c, "one").Slug = "modified"
+	byPackage.Get(c, "basic")[0].Slug = "modified"
+	c.Snapshot().Range(func(key int, item *testChannel) bool {
+		item.Slug = "modified"
+		return true
+	})
+
+	assert.Equal(t, "one", c.Get(1).Slug)
+	assert.NotSame(t, c.Get(1), c.Get(1))
+	assert.Len(t, byPackage.Get(c, "basic"), 2)
+
+	// only returned items are cloned
+	clones.Store(0)
+	c.Snapshot().Filter(func(key int, item *testChannel) bool { return key == 1 })[0].Item.Slug = "modified"
+	_, item, found := c.Snapshot().Find(func(key int, item *testChannel) bool { return item.Slug == "two" })
+	assert.True(t, found)
+	item.Slug = "modified"
+	assert.Equal(t, int64(2), clones.Load())
+	assert.Equal(t, "one", c.Get(1).Slug)
+	assert.Equal(t, "two", c.Get(2).Slug)
+}
+
+func testSnapshotDefaultClone(t *testing.T) {
+	t.Parallel()
+
+	private := 7
+	program := &testProgram{
+		ID:       1,
+		Title:    test_utils.StringPointer("News"),
+		Tags:     []string{"news", "live"},
+		Labels:   map[string]*int{"rating": test_utils.IntPointer(12)},
+		Channels: [2]*testChannel{{ID: 1, Slug: "one"}},
+		Extra:    &testChannel{ID: 2},
+		Name:     wrapperspb.String("news"),
+		private:  &private,
+	}
+
+	clone := DefaultClone(program)
+	assert.Equal(t, program, clone)
+	assert.NotSame(t, program.Title, clone.Title)
+	assert.NotSame(t, &program.Tags[0], &clone.Tags[0])
+	assert.NotSame(t, program.Labels["rating"], clone.Labels["rating"])
+	assert.NotSame(t, program.Channels[0], clone.Channels[0])
+	assert.NotSame(t, program.Extra, clone.Extra)
+	assert.NotSame(t, program.Name, clone.Name)
+	assert.True(t, proto.Equal(program.Name, clone.Name))
+	// unexported fields are copied shallowly
+	assert.Same(t, program.private, clone.private)
+
+	msg := wrapperspb.Int64(42)
+	msgClone := DefaultClone(msg)
+	assert.NotSame(t, msg, msgClone)
+	assert.True(t, proto.Equal(msg, msgClone))
+
+	assert.Nil(t, DefaultClone[testProgram](nil))
+}
@@ -0,0 +1,174 @@
+// Package sqlloader loads codebook items from SQL databases using `database/sql`.
+package sqlloader
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	codebook "github.com/moderntv/codebook-cache"
+	"github.com/moderntv/codebook-cache/internal/utils"
+)
+
+// ScanFunc scans current row of `rows` into new item.
+type ScanFunc[T any] func(rows *sql.Rows) (item *T, err error)
+
+type Params[K comparable, T any] struct {
+	DB *sql.DB
+	// Query selecting all items. With `PageSize` the query must end with placeholders of limit
+	// and offset (e.g. `LIMIT $2 OFFSET $3`) and it must order rows deterministically.
+	Query string
+	// Args of the query.
+	Args []any
+	// ScanFunc scans rows into items. When not set, columns are mapped to fields of struct `T`
+	// by `db` tags (or by case-insensitive field names).
+	ScanFunc ScanFunc[T]
+	// KeyFunc extracts key of each loaded item.
+	KeyFunc codebook.KeyFunc[K, T]
+	// PageSize loads items by pages of given size (0 loads all rows by one query).
+	// Limit and offset are passed to the query as the last two args.
+	PageSize int
+	// TxOptions of transaction in which all pages are queried, so that they are consistent
+	// with each other (read-only transaction with repeatable read isolation by default).
+	TxOptions *sql.TxOptions
+}
+
+func (p *Params[K, T]) check() error {
+	if p.DB == nil {
+		return errors.New("DB must be set")
+	}
+
+	if p.Query == "" {
+		return errors.New("Query must be set")
+	}
+
+	if p.KeyFunc == nil {
+		return errors.New("KeyFunc must be set")
+	}
+
+	if p.PageSize < 0 {
+		return errors.New("PageSize cannot be negative")
+	}
+
+	return nil
+}
+
+// New creates function loading all items by SQL query. Duplicate keys are reported as error.
+// Number of read rows and duration of queries are reported into metrics of the cache
+// (see `codebook.ReportLoadStats`).
+//
+// Pages are queried in one transaction (`Params.TxOptions`), so rows inserted or deleted
+// by concurrent transactions cannot be loaded twice or skipped.
+func New[K comparable, T any](params Params[K, T]) (loadAllFunc codebook.LoadAllFunc[K, T], err error) {
+	err = params.check()
+	if err != nil {
+		return
+	}
+
+	newScan := func() ScanFunc[T] {
+		return params.ScanFunc
+	}
+	if params.ScanFunc == nil {
+		newScan, err = structScanFunc[T]()
+		if err != nil {
+			return
+		}
+	}
+
+	txOptions := params.TxOptions
+	if txOptions == nil {
+		txOptions = &sql.TxOptions{
+			Isolation: sql.LevelRepeatableRead,
+			ReadOnly:  true,
+		}
+	}
+
+	loadAllFunc = func(ctx context.Context) (entries map[K]*T, err error) {
+		start := time.Now()
+		entries = make(map[K]*T)
+
+		var db queryer = params.DB
+		if params.PageSize > 0 {
+			var tx *sql.Tx
+			tx, err = params.DB.BeginTx(ctx, txOptions)
+			if err != nil {
+				return nil, fmt.Errorf("cannot begin transaction: %w", err)
+			}
+			defer tx.Rollback() // transaction only reads, there is nothing to commit
+			db = tx
+		}
+
+		rowCount := 0
+		for offset := 0; ; offset += params.PageSize {
+			args := params.Args
+			if params.PageSize > 0 {
+				args = append(args[:len(args):len(args)], params.PageSize, offset)
+			}
+
+			var count int
+			count, err = query(ctx, db, params, newScan(), args, offset, entries)
+			if err != nil {
+				return nil, err
+			}
+
+			rowCount += count
+			if params.PageSize == 0 || count < params.PageSize {
+				break
+			}
+		}
+
+		codebook.ReportLoadStats(ctx, codebook.LoadStats{
+			Rows:     rowCount,
+			Duration: time.Since(start),
+		})
+
+		return
+	}
+
+	return
+}
+
+// queryer is implemented by `*sql.DB` and `*sql.Tx`
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// query performs one query of page starting at `offset`, adds scanned items into `entries`
+// and returns number of read rows
+func query[K comparable, T any](
+	ctx context.Context,
+	db queryer,
+	params Params[K, T],
+	scan ScanFunc[T],
+	args []any,
+	offset int,
+	entries map[K]*T,
+) (count int, err error) {
+	rows, err := db.QueryContext(ctx, params.Query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("cannot query page at offset %d: %w", offset, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item *T
+		item, err = scan(rows)
+		if err != nil {
+			return count, fmt.Errorf("cannot scan row at offset %d: %w", offset+count, err)
+		}
+
+		err = utils.AddEntry(entries, params.KeyFunc(item), item)
+		if err != nil {
+			return
+		}
+		count++
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return count, fmt.Errorf("cannot read page at offset %d: %w", offset, err)
+	}
+	return
+}
@@ -0,0 +1,315 @@
+package sqlloader
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	codebook "github.com/moderntv/codebook-cache"
+	"github.com/moderntv/codebook-cache/internal/test_utils"
+)
+
+// fakeTable is a database driver returning the same rows for every query. When query has
+// at least two args, the last two are used as limit and offset.
+type fakeTable struct {
+	columns []string
+	rows    [][]driver.Value
+	err     error
+	queries atomic.Int64
+	// options of begun transactions
+	mu  sync.Mutex
+	txs []driver.TxOptions
+	// number of finished transactions
+	rollbacks atomic.Int64
+}
+
+func (t *fakeTable) transactions() []driver.TxOptions {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.txs
+}
+
+func (t *fakeTable) Connect(context.Context) (driver.Conn, error) {
+	return fakeConn{table: t}, nil
+}
+
+func (t *fakeTable) Driver() driver.Driver {
+	return nil
+}
+
+type fakeConn struct {
+	table *fakeTable
+}
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c fakeConn) Close() error {
+	return nil
+}
+
+func (c fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.table.mu.Lock()
+	defer c.table.mu.Unlock()
+
+	c.table.txs = append(c.table.txs, opts)
+	return fakeTx{table: c.table}, nil
+}
+
+type fakeTx struct {
+	table *fakeTable
+}
+
+func (tx fakeTx) Commit() error {
+	return errors.New("read-only transaction committed")
+}
+
+func (tx fakeTx) Rollback() error {
+	tx.table.rollbacks.Add(1)
+	return nil
+}
+
+func (c fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.table.queries.Add(1)
+	if c.table.err != nil {
+		return nil, c.table.err
+	}
+
+	rows := c.table.rows
+	if len(args) >= 2 {
+		limit := int(args[len(args)-2].Value.(int64))
+		offset := int(args[len(args)-1].Value.(int64))
+		rows = rows[min(offset, len(rows)):min(offset+limit, len(rows))]
+	}
+
+	return &fakeRows{columns: c.table.columns, rows: rows}, nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+type channel struct {
+	ID      int     `db:"channel_id"`
+	Name    string  `db:"name"`
+	Number  *int64  `db:"number"`
+	Comment *string `db:"-"`
+	Enabled bool
+}
+
+func channelID(ch *channel) int {
+	return ch.ID
+}
+
+func channelTable() *fakeTable {
+	return &fakeTable{
+		columns: []string{"channel_id", "name", "number", "ENABLED"},
+		rows: [][]driver.Value{
+			{int64(1), "CT1", int64(1), true},
+			{int64(2), "CT2", nil, false},
+			{int64(3), "Nova", int64(3), true},
+			{int64(4), "Prima", int64(4), true},
+			{int64(5), "Seznam", nil, true},
+		},
+	}
+}
+
+func TestLoader(t *testing.T) {
+	t.Run("testLoaderScan", testLoaderScan)
+	t.Run("testLoaderPaging", testLoaderPaging)
+	t.Run("testLoaderErrors", testLoaderErrors)
+	t.Run("testLoaderStats", testLoaderStats)
+}
+
+func testLoaderScan(t *testing.T) {
+	t.Parallel()
+
+	table := channelTable()
+	db := sql.OpenDB(table)
+	defer db.Close()
+
+	loadAllFunc, err := New(Params[int, channel]{
+		DB:      db,
+		Query:   "SELECT channel_id, name, number, enabled FROM channels",
+		KeyFunc: channelID,
+	})
+	assert.NoError(t, err)
+
+	entries, err := loadAllFunc(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, entries, 5)
+	assert.Equal(t, &channel{ID: 1, Name: "CT1", Number: test_utils.Int64Pointer(1), Enabled: true}, entries[1])
+	assert.Equal(t, &channel{ID: 2, Name: "CT2"}, entries[2])
+	assert.Equal(t, int64(1), table.queries.Load())
+	// single query needs no transaction
+	assert.Empty(t, table.transactions())
+
+	// custom scan function
+	namesFunc, err := New(Params[int, string]{
+		DB:    db,
+		Query: "SELECT channel_id, name, number, enabled FROM channels",
+		ScanFunc: func(rows *sql.Rows) (*string, error) {
+			var id, number sql.NullInt64
+			var name string
+			var enabled bool
+			err := rows.Scan(&id, &name, &number, &enabled)
+			return &name, err
+		},
+		KeyFunc: func(name *string) int { return len(*name) },
+	})
+	assert.NoError(t, err)
+
+	_, err = namesFunc(context.Background())
+	assert.EqualError(t, err, "duplicate key 3")
+}
+
+func testLoaderPaging(t *testing.T) {
+	t.Parallel()
+
+	for _, pageSize := range []int{1, 2, 5, 10} {
+		table := channelTable()
+		db := sql.OpenDB(table)
+
+		loadAllFunc, err := New(Params[int, channel]{
+			DB:       db,
+			Query:    "SELECT channel_id, name, number, enabled FROM channels WHERE enabled = $1 ORDER BY channel_id LIMIT $2 OFFSET $3",
+			Args:     []any{true},
+			KeyFunc:  channelID,
+			PageSize: pageSize,
+		})
+		assert.NoError(t, err)
+
+		entries, err := loadAllFunc(context.Background())
+		assert.NoError(t, err)
+		assert.Len(t, entries, 5, "page size %d", pageSize)
+		// the last page must be shorter than page size
+		assert.Equal(t, int64(5/pageSize+1), table.queries.Load(), "page size %d", pageSize)
+		// all pages are queried in one read-only transaction
+		assert.Equal(t, []driver.TxOptions{{
+			Isolation: driver.IsolationLevel(sql.LevelRepeatableRead),
+			ReadOnly:  true,
+		}}, table.transactions())
+		assert.Equal(t, int64(1), table.rollbacks.Load())
+
+		assert.NoError(t, db.Close())
+	}
+}
+
+func testLoaderErrors(t *testing.T) {
+	t.Parallel()
+
+	table := channelTable()
+	db := sql.OpenDB(table)
+	defer db.Close()
+
+	_, err := New(Params[int, channel]{Query: "SELECT", KeyFunc: channelID})
+	assert.EqualError(t, err, "DB must be set")
+	_, err = New(Params[int, channel]{DB: db, KeyFunc: channelID})
+	assert.EqualError(t, err, "Query must be set")
+	_, err = New(Params[int, channel]{DB: db, Query: "SELECT"})
+	assert.EqualError(t, err, "KeyFunc must be set")
+	_, err = New(Params[int, channel]{DB: db, Query: "SELECT", KeyFunc: channelID, PageSize: -1})
+	assert.EqualError(t, err, "PageSize cannot be negative")
+	_, err = New(Params[int, int]{DB: db, Query: "SELECT", KeyFunc: func(i *int) int { return *i }})
+	assert.EqualError(t, err, "ScanFunc must be set when items are not structs")
+
+	// unknown column
+	table.columns = []string{"channel_id", "name", "number", "comment"}
+	loadAllFunc, err := New(Params[int, channel]{DB: db, Query: "SELECT", KeyFunc: channelID})
+	assert.NoError(t, err)
+	_, err = loadAllFunc(context.Background())
+	assert.EqualError(t, err, "cannot scan row at offset 0: no field for column comment")
+
+	// query error
+	queryErr := errors.New("connection refused")
+	table.err = queryErr
+	_, err = loadAllFunc(context.Background())
+	assert.EqualError(t, err, "cannot query page at offset 0: connection refused")
+	assert.ErrorIs(t, err, queryErr)
+
+	// error of the second page
+	table.err = nil
+	table.columns = channelTable().columns
+	pagedFunc, err := New(Params[int, channel]{DB: db, Query: "SELECT", KeyFunc: channelID, PageSize: 3})
+	assert.NoError(t, err)
+	table.rows = append(table.rows, []driver.Value{"six", "Six", nil, true})
+	_, err = pagedFunc(context.Background())
+	assert.ErrorContains(t, err, "cannot scan row at offset 5: ")
+	assert.Equal(t, int64(1), table.rollbacks.Load())
+}
+
+func testLoaderStats(t *testing.T) {
+	t.Parallel()
+
+	db := sql.OpenDB(channelTable())
+	defer db.Close()
+
+	loadAllFunc, err := New(Params[int, channel]{
+		DB:       db,
+		Query:    "SELECT channel_id, name, number, enabled FROM channels LIMIT $1 OFFSET $2",
+		KeyFunc:  channelID,
+		PageSize: 2,
+	})
+	assert.NoError(t, err)
+
+	registry := prometheus.NewRegistry()
+	c, err := codebook.New(codebook.Params[int, channel]{
+		Context:           context.Background(),
+		Log:               test_utils.Logger(),
+		MetricsRegisterer: registry,
+		Name:              "channels",
+		LoadAllFunc:       loadAllFunc,
+		Timeouts: codebook.Timeouts{
+			ReloadInterval: 10 * time.Second,
+		},
+	})
+	assert.NoError(t, err)
+	defer c.Close(context.Background())
+
+	assert.Len(t, c.GetAll(), 5)
+	assert.NoError(t, c.InvalidateAndWait(context.Background()))
+
+	assert.Equal(t, 1, testutil.CollectAndCount(registry, "codebook_cache_source_row_count"))
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP codebook_cache_source_row_count Total number of rows read from data source by load functions
+# TYPE codebook_cache_source_row_count counter
+codebook_cache_source_row_count{cache_instance="0",name="channels"} 10
+`), "codebook_cache_source_row_count"))
+	assert.Equal(t, 1, testutil.CollectAndCount(registry, "codebook_cache_source_query_duration_seconds"))
+}
@@ -0,0 +1,82 @@
+package sqlloader
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// structScanFunc returns function creating scan function of one query, which scans columns
+// into fields of struct `T`
+func structScanFunc[T any]() (newScan func() ScanFunc[T], err error) {
+	itemType := reflect.TypeOf((*T)(nil)).Elem()
+	if itemType.Kind() != reflect.Struct {
+		return nil, errors.New("ScanFunc must be set when items are not structs")
+	}
+
+	byName := make(map[string][]int, itemType.NumField())
+	for _, field := range reflect.VisibleFields(itemType) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+
+		name := strings.ToLower(field.Name)
+		tag, ok := field.Tag.Lookup("db")
+		if ok {
+			name, _, _ = strings.Cut(tag, ",")
+			if name == "-" {
+				continue
+			}
+		}
+		byName[name] = field.Index
+	}
+
+	newScan = func() ScanFunc[T] {
+		// fields are resolved from columns of the first row, they are the same for all rows of the query
+		var fields [][]int
+		return func(rows *sql.Rows) (item *T, err error) {
+			if fields == nil {
+				fields, err = columnFields(rows, byName)
+				if err != nil {
+					return
+				}
+			}
+
+			item = new(T)
+			value := reflect.ValueOf(item).Elem()
+			dest := make([]any, len(fields))
+			for i, field := range fields {
+				dest[i] = value.FieldByIndex(field).Addr().Interface()
+			}
+
+			err = rows.Scan(dest...)
+			return
+		}
+	}
+
+	return
+}
+
+// columnFields returns index of field for each column of `rows`
+func columnFields(rows *sql.Rows, byName map[string][]int) (fields [][]int, err error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return
+	}
+
+	fields = make([][]int, len(columns))
+	for i, column := range columns {
+		index, ok := byName[column]
+		if !ok {
+			index, ok = byName[strings.ToLower(column)]
+		}
+		if !ok {
+			return nil, fmt.Errorf("no field for column %s", column)
+		}
+		fields[i] = index
+	}
+
+	return
+}
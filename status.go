@@ -0,0 +1,140 @@
+package codebook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/moderntv/cadre/status"
+)
+
+// ErrStale is returned by health check when data are older than `Timeouts.MaxStaleness`.
+var ErrStale = errors.New("cache data are stale")
+
+// Status describes state of cache data and its reloading.
+type Status struct {
+	// LastSuccess is start of the last successful load (zero when data were restored
+	// from `Params.SnapshotStore` and no load succeeded yet).
+	LastSuccess time.Time
+	// LastAttempt is start of the last finished load.
+	LastAttempt time.Time
+	// LastError is error of the last finished load (nil when it succeeded).
+	LastError error
+	// ConsecutiveFailures is number of failed loads since the last successful one.
+	ConsecutiveFailures int
+	// ItemCount is number of items in cache.
+	ItemCount int
+	// NextReload is time of the next periodic reload or retry (zero when not planned).
+	NextReload time.Time
+	// Reloading is true when a load is running.
+	Reloading bool
+	// Stale is true when data were restored from `Params.SnapshotStore` (see `Cache.IsStale`).
+	Stale bool
+}
+
+// HealthChecker reports health of a component. Returns nil when the component is healthy.
+// It is implemented by `Cache` and `Group` and can be reported into cadre status by `ReportHealth`.
+type HealthChecker interface {
+	HealthCheck() error
+}
+
+// Status returns current state of cache data and its reloading.
+func (c *Cache[K, T]) Status() (s Status) {
+	c.mu.Lock()
+	s = Status{
+		LastSuccess:         c.lastSuccess,
+		LastAttempt:         c.lastAttempt,
+		LastError:           c.lastErr,
+		ConsecutiveFailures: c.consecutiveFailures,
+		Reloading:           c.isReloading,
+	}
+	if c.nextReload != nil {
+		s.NextReload = *c.nextReload
+	}
+	c.mu.Unlock()
+
+	s.ItemCount = len(c.loadData().entries)
+	s.Stale = c.IsStale()
+
+	return
+}
+
+// HealthCheck returns error when cache has been closed or when its data are older than
+// `Timeouts.MaxStaleness` (`ErrStale` wrapping the last load error).
+func (c *Cache[K, T]) HealthCheck() error {
+	if c.isClosed() {
+		return ErrClosed
+	}
+
+	if c.timeouts.MaxStaleness == 0 {
+		return nil
+	}
+
+	s := c.Status()
+	if s.LastSuccess.IsZero() {
+		return staleError("no successful load", s.LastError)
+	}
+
+	age := time.Since(s.LastSuccess)
+	if age > c.timeouts.MaxStaleness {
+		return staleError(fmt.Sprintf("last successful load %s ago", age.Round(time.Second)), s.LastError)
+	}
+
+	return nil
+}
+
+// staleError returns `ErrStale` with `reason` wrapping the last load error (when set)
+func staleError(reason string, lastErr error) error {
+	if lastErr == nil {
+		return fmt.Errorf("%w: %s", ErrStale, reason)
+	}
+
+	return fmt.Errorf("%w: %s: %w", ErrStale, reason, lastErr)
+}
+
+// HealthCheck returns joined errors of caches which are not loaded or not healthy.
+func (g *Group) HealthCheck() error {
+	g.mu.Lock()
+	members := g.members
+	g.mu.Unlock()
+
+	errs := make([]error, 0)
+	for _, member := range members {
+		err := member.healthCheck()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cache %s: %w", member.name(), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ReportHealth checks health of `checker` every `interval` and sets result into cadre component
+// status (`status.OK` or `status.ERROR` with error message) until `ctx` is done.
+func ReportHealth(ctx context.Context, checker HealthChecker, component *status.ComponentStatus, interval time.Duration) {
+	report := func() {
+		err := checker.HealthCheck()
+		if err != nil {
+			component.SetStatus(status.ERROR, err.Error())
+			return
+		}
+
+		component.SetStatus(status.OK, "")
+	}
+
+	report()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				report()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,163 @@
+package codebook
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/moderntv/cadre/status"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatus(t *testing.T) {
+	t.Run("testStatus", testStatus)
+	t.Run("testStatusHealthCheck", testStatusHealthCheck)
+	t.Run("testStatusGroupHealthCheck", testStatusGroupHealthCheck)
+	t.Run("testStatusReportHealth", testStatusReportHealth)
+}
+
+// failingParams returns params of counting cache (see `countingParams`) which fails while `fail` is set
+func failingParams(loadCount, loadDelay *atomic.Int64, fail *atomic.Bool) Params[string, int] {
+	var loadErr atomic.Value
+	params := countingParams(loadCount, loadDelay, &loadErr)
+	loadAllFunc := params.LoadAllFunc
+	params.LoadAllFunc = func(ctx context.Context) (map[string]*int, error) {
+		if fail.Load() {
+			return nil, errors.New("cannot load")
+		}
+
+		return loadAllFunc(ctx)
+	}
+
+	return params
+}
+
+func testStatus(t *testing.T) {
+	t.Parallel()
+
+	var loadCount, loadDelay atomic.Int64
+	var fail atomic.Bool
+
+	start := time.Now()
+	c, err := New(failingParams(&loadCount, &loadDelay, &fail))
+	assert.NoError(t, err)
+
+	s := c.Status()
+	assert.False(t, s.LastSuccess.Before(start))
+	assert.Equal(t, s.LastSuccess, s.LastAttempt)
+	assert.NoError(t, s.LastError)
+	assert.Equal(t, 0, s.ConsecutiveFailures)
+	assert.Equal(t, 1, s.ItemCount)
+	assert.WithinDuration(t, s.LastSuccess.Add(10*time.Second), s.NextReload, time.Millisecond)
+	assert.False(t, s.Reloading)
+	assert.False(t, s.Stale)
+
+	// failures are counted and the last successful load is kept
+	fail.Store(true)
+	assert.Error(t, c.InvalidateAndWait(context.Background()))
+	assert.Error(t, c.InvalidateAndWait(context.Background()))
+	failed := c.Status()
+	assert.Equal(t, s.LastSuccess, failed.LastSuccess)
+	assert.True(t, failed.LastAttempt.After(s.LastAttempt))
+	assert.EqualError(t, failed.LastError, "cannot load")
+	assert.Equal(t, 2, failed.ConsecutiveFailures)
+	assert.Equal(t, 1, failed.ItemCount)
+
+	// running reload
+	fail.Store(false)
+	loadDelay.Store(int64(100 * time.Millisecond))
+	c.InvalidateAll()
+	time.Sleep(50 * time.Millisecond)
+	assert.True(t, c.Status().Reloading)
+	time.Sleep(100 * time.Millisecond)
+
+	s = c.Status()
+	assert.False(t, s.Reloading)
+	assert.True(t, s.LastSuccess.After(failed.LastAttempt))
+	assert.NoError(t, s.LastError)
+	assert.Equal(t, 0, s.ConsecutiveFailures)
+}
+
+func testStatusHealthCheck(t *testing.T) {
+	t.Parallel()
+
+	var loadCount, loadDelay atomic.Int64
+	var fail atomic.Bool
+
+	params := failingParams(&loadCount, &loadDelay, &fail)
+	params.Timeouts.MaxStaleness = 200 * time.Millisecond
+	c, err := New(params)
+	assert.NoError(t, err)
+	assert.NoError(t, c.HealthCheck())
+
+	fail.Store(true)
+	assert.Error(t, c.InvalidateAndWait(context.Background()))
+	assert.NoError(t, c.HealthCheck())
+
+	// data are too old
+	time.Sleep(250 * time.Millisecond)
+	err = c.HealthCheck()
+	assert.ErrorIs(t, err, ErrStale)
+	assert.ErrorContains(t, err, "cannot load")
+
+	fail.Store(false)
+	assert.NoError(t, c.InvalidateAndWait(context.Background()))
+	assert.NoError(t, c.HealthCheck())
+
+	assert.NoError(t, c.Close(context.Background()))
+	assert.ErrorIs(t, c.HealthCheck(), ErrClosed)
+
+	// without max staleness, cache is always healthy until closed
+	params.Timeouts.MaxStaleness = 0
+	c, err = New(params)
+	assert.NoError(t, err)
+	fail.Store(true)
+	assert.Error(t, c.InvalidateAndWait(context.Background()))
+	assert.NoError(t, c.HealthCheck())
+}
+
+func testStatusGroupHealthCheck(t *testing.T) {
+	t.Parallel()
+
+	var running, maxRunning atomic.Int64
+
+	g, err := NewGroup(GroupParams{})
+	assert.NoError(t, err)
+	_, err = Register(g, slowIntsParams("healthy", 0, &running, &maxRunning))
+	assert.NoError(t, err)
+	assert.ErrorContains(t, g.HealthCheck(), "cache healthy: not loaded")
+
+	assert.NoError(t, g.Start(context.Background()))
+	assert.NoError(t, g.HealthCheck())
+
+	assert.NoError(t, g.Close(context.Background()))
+	assert.ErrorIs(t, g.HealthCheck(), ErrClosed)
+}
+
+func testStatusReportHealth(t *testing.T) {
+	t.Parallel()
+
+	var loadCount, loadDelay atomic.Int64
+	var fail atomic.Bool
+
+	params := failingParams(&loadCount, &loadDelay, &fail)
+	params.Timeouts.MaxStaleness = 100 * time.Millisecond
+	c, err := New(params)
+	assert.NoError(t, err)
+
+	component, err := status.NewStatus("testing").Register("testing_cache")
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ReportHealth(ctx, c, component, 50*time.Millisecond)
+	assert.Equal(t, status.OK, component.Status())
+
+	fail.Store(true)
+	assert.Error(t, c.InvalidateAndWait(context.Background()))
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, status.ERROR, component.Status())
+	assert.Contains(t, component.Message(), "cannot load")
+}
@@ -2,9 +2,15 @@ package codebook
 
 import (
 	"errors"
+	"math"
 	"time"
+
+	"github.com/moderntv/codebook-cache/internal/utils"
 )
 
+// defaultFullReloadInterval is used when `Timeouts.FullReloadInterval` is not set
+const defaultFullReloadInterval = time.Hour
+
 type Timeouts struct {
 	// ReloadInterval specifies how often should the cache be reloaded.
 	// This duration is each time randomized by `Ranomizer` attribute.
@@ -28,6 +34,46 @@ type Timeouts struct {
 	// e.g. real cache reload interval is set as `ReloadInterval` +/- `ReloadInterval` * `Ranomizer`.
 	// All durations are being randomized each time they are set.
 	Ranomizer float64
+
+	// FullReloadInterval specifies how often should the cache load all items when loading changes
+	// is enabled (`Params.LoadChangesFunc`); other reloads load only changes.
+	// Full reload heals possible drift between cached items and data source.
+	// Value 0 means default interval of 1 hour.
+	FullReloadInterval time.Duration
+
+	// LoadTimeout limits duration of each load (context passed to load functions is cancelled
+	// after it). Timed out load is handled as failed load. Value 0 means no limit.
+	LoadTimeout time.Duration
+
+	// MaxStaleness specifies how old data can be (time since the last successful load) before
+	// the cache reports unhealthy (see `Cache.HealthCheck`). Value 0 means no limit.
+	MaxStaleness time.Duration
+
+	// Retry specifies how soon should be failed reload retried.
+	// Without retry policy, the next reload is planned according to `ReloadInterval` even after failure.
+	Retry RetryPolicy
+}
+
+// RetryPolicy plans retries of failed reloads with exponential backoff.
+// Retries are enabled when `InitialBackoff` is set.
+type RetryPolicy struct {
+	// InitialBackoff specifies delay after the first failed reload.
+	InitialBackoff time.Duration
+
+	// MaxBackoff limits delay between retries (0 means no limit).
+	// Delay never exceeds `Timeouts.ReloadInterval` (when set).
+	MaxBackoff time.Duration
+
+	// Multiplier specifies how much the delay grows after each failed retry. Must be at least 1,
+	// value 0 is treated as 2.
+	Multiplier float64
+
+	// Jitter specifies how much the delays should be randomized (see `Timeouts.Ranomizer`).
+	Jitter float64
+
+	// MaxAttempts limits number of retries after consecutive failures (0 means no limit).
+	// When all retries fail, the next reload is planned according to `Timeouts.ReloadInterval`.
+	MaxAttempts int
 }
 
 func (t *Timeouts) check() error {
@@ -42,5 +88,64 @@ func (t *Timeouts) check() error {
 		return errors.New("Ranomizer cannot be greater than 1")
 	}
 
+	if t.FullReloadInterval < 0 {
+		return errors.New("FullReloadInterval cannot be negative")
+	}
+
+	if t.LoadTimeout < 0 {
+		return errors.New("LoadTimeout cannot be negative")
+	}
+
+	if t.MaxStaleness < 0 {
+		return errors.New("MaxStaleness cannot be negative")
+	}
+
+	return t.Retry.check()
+}
+
+func (r *RetryPolicy) check() error {
+	if r.InitialBackoff < 0 || r.MaxBackoff < 0 {
+		return errors.New("retry backoff cannot be negative")
+	}
+
+	if r.MaxBackoff > 0 && r.MaxBackoff < r.InitialBackoff {
+		return errors.New("MaxBackoff must be greater than or equal to InitialBackoff")
+	}
+
+	if r.Multiplier != 0 && r.Multiplier < 1 {
+		return errors.New("Multiplier cannot be less than 1")
+	}
+
+	if r.Jitter < 0 || r.Jitter > 1 {
+		return errors.New("Jitter must be between 0 and 1")
+	}
+
+	if r.MaxAttempts < 0 {
+		return errors.New("MaxAttempts cannot be negative")
+	}
+
 	return nil
 }
+
+// backoff returns delay before the next retry after `failures` consecutive failures.
+// Returns 0 when retries are disabled or all attempts have been used.
+func (r *RetryPolicy) backoff(failures int) time.Duration {
+	if r.InitialBackoff == 0 || failures < 1 || (r.MaxAttempts > 0 && failures > r.MaxAttempts) {
+		return 0
+	}
+
+	multiplier := r.Multiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+
+	backoff := float64(r.InitialBackoff) * math.Pow(multiplier, float64(failures-1))
+	if r.MaxBackoff > 0 && backoff > float64(r.MaxBackoff) {
+		backoff = float64(r.MaxBackoff)
+	}
+	if backoff > math.MaxInt64 {
+		backoff = math.MaxInt64
+	}
+
+	return utils.RandomizeDuration(time.Duration(backoff), r.Jitter)
+}
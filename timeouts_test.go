@@ -41,3 +41,48 @@ func TestTimeoutsRanomizerValue(t *testing.T) {
 		assert.Equal(t, expected, timeouts.check() == nil)
 	}
 }
+
+func TestTimeoutsRetryPolicy(t *testing.T) {
+	expetedResult := map[RetryPolicy]bool{
+		{}:                             true,
+		{InitialBackoff: time.Second}:  true,
+		{InitialBackoff: -time.Second}: false,
+		{InitialBackoff: time.Second, MaxBackoff: time.Minute, Multiplier: 1.5, Jitter: 0.1, MaxAttempts: 5}: true,
+		{InitialBackoff: time.Minute, MaxBackoff: time.Second}:                                               false,
+		{InitialBackoff: time.Second, Multiplier: 0.5}:                                                       false,
+		{InitialBackoff: time.Second, Jitter: 1.5}:                                                           false,
+		{InitialBackoff: time.Second, MaxAttempts: -1}:                                                       false,
+	}
+
+	for retry, expected := range expetedResult {
+		timeouts := Timeouts{
+			Retry: retry,
+		}
+		assert.Equal(t, expected, timeouts.check() == nil, "%+v", retry)
+	}
+}
+
+func TestTimeoutsRetryBackoff(t *testing.T) {
+	retry := RetryPolicy{
+		InitialBackoff: time.Second,
+		MaxBackoff:     10 * time.Second,
+		MaxAttempts:    6,
+	}
+
+	assert.Equal(t, time.Duration(0), retry.backoff(0))
+	assert.Equal(t, 1*time.Second, retry.backoff(1))
+	assert.Equal(t, 2*time.Second, retry.backoff(2))
+	assert.Equal(t, 4*time.Second, retry.backoff(3))
+	assert.Equal(t, 8*time.Second, retry.backoff(4))
+	assert.Equal(t, 10*time.Second, retry.backoff(5))
+	assert.Equal(t, 10*time.Second, retry.backoff(6))
+	assert.Equal(t, time.Duration(0), retry.backoff(7))
+
+	retry.Multiplier = 3
+	retry.Jitter = 0.5
+	backoff := retry.backoff(2)
+	assert.GreaterOrEqual(t, backoff, 1500*time.Millisecond)
+	assert.LessOrEqual(t, backoff, 4500*time.Millisecond)
+
+	assert.Equal(t, time.Duration(0), (&RetryPolicy{}).backoff(1))
+}
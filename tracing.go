@@ -0,0 +1,114 @@
+package codebook
+
+import (
+	"context"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	metrics_pkg "github.com/moderntv/codebook-cache/internal/metrics"
+)
+
+const tracerName = "github.com/moderntv/codebook-cache"
+
+// maxPendingSpans limits number of invalidation spans linked from one reload
+const maxPendingSpans = 32
+
+// natsHeaderCarrier adapts NATS message headers to `propagation.TextMapCarrier`
+type natsHeaderCarrier nats.Header
+
+func (c natsHeaderCarrier) Get(key string) string {
+	return nats.Header(c).Get(key)
+}
+
+func (c natsHeaderCarrier) Set(key string, value string) {
+	nats.Header(c).Set(key, value)
+}
+
+func (c natsHeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// InjectSpan stores span from `ctx` into NATS message headers, so the reload caused
+// by the invalidation message becomes its child. Headers are created when `msg` has none.
+func InjectSpan(ctx context.Context, propagator propagation.TextMapPropagator, msg *nats.Msg) {
+	if msg.Header == nil {
+		msg.Header = nats.Header{}
+	}
+
+	propagator.Inject(ctx, natsHeaderCarrier(msg.Header))
+}
+
+// addPendingSpan remembers span propagated in invalidation event `metadata`
+// for the following reload
+func (c *Cache[K, T]) addPendingSpan(metadata map[string]string) {
+	if len(metadata) == 0 {
+		return
+	}
+
+	ctx := c.propagator.Extract(context.Background(), propagation.MapCarrier(metadata))
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.pendingSpans) < maxPendingSpans {
+		c.pendingSpans = append(c.pendingSpans, spanContext)
+	}
+}
+
+// startReloadSpan starts span of reload. The first span of invalidation messages
+// received since the last reload becomes its parent, other ones are linked.
+func (c *Cache[K, T]) startReloadSpan(ctx context.Context, reason reloadReason) (context.Context, trace.Span) {
+	c.mu.Lock()
+	pending := c.pendingSpans
+	c.pendingSpans = nil
+	c.mu.Unlock()
+
+	links := make([]trace.Link, 0, len(pending))
+	for i, spanContext := range pending {
+		if i == 0 {
+			ctx = trace.ContextWithRemoteSpanContext(ctx, spanContext)
+			continue
+		}
+		links = append(links, trace.Link{SpanContext: spanContext})
+	}
+
+	return c.tracer.Start(ctx, "codebook.reload",
+		trace.WithLinks(links...),
+		trace.WithAttributes(
+			attribute.String("codebook.cache", c.name),
+			attribute.String("codebook.reload.reason", string(reason)),
+		),
+	)
+}
+
+// endReloadSpan records result of reload into `span` and ends it
+func (c *Cache[K, T]) endReloadSpan(span trace.Span, start time.Time, data *dataset[K, T], result string, err error) {
+	success := err == nil
+	span.SetAttributes(
+		attribute.String("codebook.reload.result", result),
+		attribute.Bool("codebook.reload.success", success),
+		attribute.Float64("codebook.reload.duration_s", time.Since(start).Seconds()),
+	)
+	if data != nil {
+		span.SetAttributes(attribute.Int("codebook.items.count", len(data.entries)))
+	}
+	if result == metrics_pkg.ResultFailure {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
@@ -0,0 +1,167 @@
+package codebook
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/moderntv/codebook-cache/internal/test_utils"
+)
+
+func TestTracing(t *testing.T) {
+	t.Run("testTracingReload", testTracingReload)
+	t.Run("testTracingInvalidation", testTracingInvalidation)
+}
+
+// reloadSpans returns ended reload spans
+func reloadSpans(recorder *tracetest.SpanRecorder) (spans []sdktrace.ReadOnlySpan) {
+	for _, span := range recorder.Ended() {
+		if span.Name() == "codebook.reload" {
+			spans = append(spans, span)
+		}
+	}
+	return
+}
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, attr := range span.Attributes() {
+		if attr.Key == key {
+			return attr.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func testTracingReload(t *testing.T) {
+	t.Parallel()
+
+	recorder := tracetest.NewSpanRecorder()
+	var fail atomic.Bool
+
+	c, err := New(Params[string, int]{
+		Context:        context.Background(),
+		Log:            test_utils.Logger(),
+		Name:           "testing_cache",
+		TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)),
+		LoadAllFunc: func(ctx context.Context) (map[string]*int, error) {
+			if fail.Load() {
+				return nil, errors.New("database is down")
+			}
+
+			return map[string]*int{
+				"key1": test_utils.IntPointer(1),
+				"key2": test_utils.IntPointer(2),
+			}, nil
+		},
+	})
+	assert.NoError(t, err)
+
+	fail.Store(true)
+	assert.Error(t, c.reload(reasonManual))
+
+	spans := reloadSpans(recorder)
+	assert.Len(t, spans, 2)
+
+	assert.Equal(t, "testing_cache", spanAttribute(spans[0], "codebook.cache").AsString())
+	assert.Equal(t, "initial", spanAttribute(spans[0], "codebook.reload.reason").AsString())
+	assert.Equal(t, int64(2), spanAttribute(spans[0], "codebook.items.count").AsInt64())
+	assert.True(t, spanAttribute(spans[0], "codebook.reload.success").AsBool())
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+
+	assert.Equal(t, "manual", spanAttribute(spans[1], "codebook.reload.reason").AsString())
+	assert.False(t, spanAttribute(spans[1], "codebook.reload.success").AsBool())
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, "database is down", spans[1].Status().Description)
+}
+
+func testTracingInvalidation(t *testing.T) {
+	t.Parallel()
+
+	recorder := tracetest.NewSpanRecorder()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	nc := test_utils.NatsConnection(t)
+
+	var loadCtx atomic.Value
+	c, err := New(Params[string, int]{
+		Context:        context.Background(),
+		Log:            test_utils.Logger(),
+		Name:           "testing_cache",
+		TracerProvider: tracerProvider,
+		LoadAllFunc: func(ctx context.Context) (map[string]*int, error) {
+			loadCtx.Store(ctx)
+			return map[string]*int{
+				"key1": test_utils.IntPointer(1),
+			}, nil
+		},
+		Invalidations: &Invalidations{
+			Nats:   nc,
+			Prefix: "tracing.",
+			Messages: map[string]proto.Message{
+				"invalidate": &wrapperspb.StringValue{},
+			},
+		},
+		Timeouts: Timeouts{
+			ReloadInterval: 5 * time.Second,
+			ReloadDelay:    100 * time.Millisecond,
+		},
+	})
+	assert.NoError(t, err)
+
+	// test connection does not receive its own messages
+	publisher, err := nats.Connect(nc.ConnectedUrl())
+	assert.NoError(t, err)
+	defer publisher.Close()
+
+	// publishes invalidation message with propagated span
+	publish := func() (span sdktrace.ReadOnlySpan) {
+		ctx, s := tracerProvider.Tracer("test").Start(context.Background(), "admin.edit")
+		defer s.End()
+
+		data, err := proto.Marshal(wrapperspb.String("key1"))
+		assert.NoError(t, err)
+		msg := nats.NewMsg("tracing.invalidate")
+		msg.Data = data
+		InjectSpan(ctx, propagation.TraceContext{}, msg)
+		assert.NoError(t, publisher.PublishMsg(msg))
+
+		return s.(sdktrace.ReadOnlySpan)
+	}
+	// reload outside of aggregation window is immediate
+	c.InvalidateAll()
+	time.Sleep(20 * time.Millisecond)
+	// two invalidations aggregated into one reload
+	first := publish()
+	second := publish()
+	time.Sleep(300 * time.Millisecond)
+
+	spans := reloadSpans(recorder)
+	if assert.Len(t, spans, 3) {
+		assert.Equal(t, "manual", spanAttribute(spans[1], "codebook.reload.reason").AsString())
+		reload := spans[2]
+		assert.Equal(t, "invalidation", spanAttribute(reload, "codebook.reload.reason").AsString())
+		assert.Equal(t, first.SpanContext().TraceID(), reload.SpanContext().TraceID())
+		assert.Equal(t, first.SpanContext().SpanID(), reload.Parent().SpanID())
+		if assert.Len(t, reload.Links(), 1) {
+			assert.Equal(t, second.SpanContext().SpanID(), reload.Links()[0].SpanContext.SpanID())
+		}
+	}
+
+	// loader receives context of reload span
+	ctx := loadCtx.Load().(context.Context)
+	assert.Equal(t, spans[len(spans)-1].SpanContext().SpanID(), trace.SpanContextFromContext(ctx).SpanID())
+
+	assert.NoError(t, c.Close(context.Background()))
+}
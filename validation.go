@@ -0,0 +1,70 @@
+package codebook
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrRejected is returned (wrapped) when loaded items are rejected by one of `Params.Validators`.
+var ErrRejected = errors.New("loaded items rejected")
+
+// ValidateFunc checks newly loaded items before they replace the current ones.
+// `oldEntries` is nil when the cache is being created.
+// When error is returned, loading is treated as failed and the current items are kept.
+type ValidateFunc[K comparable, T any] func(oldEntries, newEntries map[K]*T) error
+
+// RejectEmpty rejects empty result unless the current items are empty as well
+// (or the cache is being created).
+func RejectEmpty[K comparable, T any]() ValidateFunc[K, T] {
+	return func(oldEntries, newEntries map[K]*T) error {
+		if len(newEntries) == 0 && len(oldEntries) > 0 {
+			return fmt.Errorf("empty result (current count %d)", len(oldEntries))
+		}
+
+		return nil
+	}
+}
+
+// RejectShrink rejects result when items count drops by more than `ratio` (0.2 means 20%)
+// compared to the current items.
+func RejectShrink[K comparable, T any](ratio float64) ValidateFunc[K, T] {
+	return func(oldEntries, newEntries map[K]*T) error {
+		oldCount := len(oldEntries)
+		newCount := len(newEntries)
+		if oldCount == 0 || newCount >= oldCount {
+			return nil
+		}
+
+		drop := float64(oldCount-newCount) / float64(oldCount)
+		if drop > ratio {
+			return fmt.Errorf("items count dropped by %.1f%% (from %d to %d)", drop*100, oldCount, newCount)
+		}
+
+		return nil
+	}
+}
+
+func (c *Cache[K, T]) validate(entries map[K]*T) (err error) {
+	if len(c.validators) == 0 {
+		return
+	}
+
+	var oldEntries map[K]*T
+	oldData, ok := c.data.Load().(*dataset[K, T])
+	if ok {
+		oldEntries = oldData.entries
+	}
+
+	for _, validator := range c.validators {
+		err = validator(oldEntries, entries)
+		if err != nil {
+			if c.metrics != nil {
+				c.metrics.RejectedLoadCount.Inc()
+			}
+
+			return fmt.Errorf("%w: %w", ErrRejected, err)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,92 @@
+package codebook
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moderntv/codebook-cache/internal/test_utils"
+)
+
+func TestValidation(t *testing.T) {
+	t.Run("testValidationRejectEmpty", testValidationRejectEmpty)
+	t.Run("testValidationRejectShrink", testValidationRejectShrink)
+	t.Run("testValidationReload", testValidationReload)
+}
+
+func entriesOfSize(size int) map[int]*int {
+	entries := make(map[int]*int, size)
+	for i := 0; i < size; i++ {
+		entries[i] = test_utils.IntPointer(i)
+	}
+
+	return entries
+}
+
+func testValidationRejectEmpty(t *testing.T) {
+	t.Parallel()
+
+	validate := RejectEmpty[int, int]()
+	assert.NoError(t, validate(nil, entriesOfSize(0)))
+	assert.NoError(t, validate(entriesOfSize(0), entriesOfSize(0)))
+	assert.NoError(t, validate(entriesOfSize(5), entriesOfSize(1)))
+	assert.Error(t, validate(entriesOfSize(5), entriesOfSize(0)))
+}
+
+func testValidationRejectShrink(t *testing.T) {
+	t.Parallel()
+
+	validate := RejectShrink[int, int](0.2)
+	assert.NoError(t, validate(nil, entriesOfSize(0)))
+	assert.NoError(t, validate(entriesOfSize(10), entriesOfSize(20)))
+	assert.NoError(t, validate(entriesOfSize(10), entriesOfSize(8)))
+	assert.Error(t, validate(entriesOfSize(10), entriesOfSize(7)))
+	assert.Error(t, validate(entriesOfSize(10), entriesOfSize(0)))
+}
+
+func testValidationReload(t *testing.T) {
+	t.Parallel()
+
+	var size atomic.Int64
+	size.Store(10)
+
+	c, err := New(Params[int, int]{
+		Context:         context.Background(),
+		Log:             test_utils.Logger(),
+		MetricsRegistry: test_utils.Metrics("testing_cache"),
+		Name:            "testing_cache",
+		LoadAllFunc: func(ctx context.Context) (map[int]*int, error) {
+			return entriesOfSize(int(size.Load())), nil
+		},
+		Validators: []ValidateFunc[int, int]{
+			RejectEmpty[int, int](),
+			func(oldEntries, newEntries map[int]*int) error {
+				if len(newEntries) == 13 {
+					return errors.New("unlucky")
+				}
+				return nil
+			},
+		},
+		Timeouts: Timeouts{
+			ReloadInterval: 5 * time.Second,
+		},
+	})
+	assert.NoError(t, err)
+
+	// rejected -> old data kept
+	size.Store(0)
+	assert.ErrorIs(t, c.reload(reasonManual), ErrRejected)
+	assert.Len(t, c.GetAll(), 10)
+
+	size.Store(13)
+	assert.ErrorIs(t, c.reload(reasonManual), ErrRejected)
+	assert.Len(t, c.GetAll(), 10)
+
+	size.Store(5)
+	assert.NoError(t, c.reload(reasonManual))
+	assert.Len(t, c.GetAll(), 5)
+}
@@ -0,0 +1,71 @@
+package codebook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Version identifies state of data source, e.g. database sequence or the latest modification
+// time in unix nanoseconds. It must increase with every change of data source.
+type Version uint64
+
+// waitForVersionRetryDelay specifies delay between reloads which did not load required version
+const waitForVersionRetryDelay = 100 * time.Millisecond
+
+// Version returns version of data source the current items were loaded at.
+// Without `Params.LoadAllVersionedFunc`, it is version returned by the last `Params.LoadChangesFunc`
+// call (0 before the first one) or always 0 when `Params.LoadChangesFunc` is not set either.
+func (c *Cache[K, T]) Version() Version {
+	return c.loadData().version
+}
+
+// WaitForVersion blocks until cache holds items loaded at version `version` or newer
+// (read-your-writes consistency). When the current version is older, all items are invalidated
+// (respecting `Timeouts.ReloadDelay`) and reloads are repeated until the version is reached
+// or `ctx` is done.
+// Requires `Params.LoadAllVersionedFunc` or `Params.LoadChangesFunc`.
+func (c *Cache[K, T]) WaitForVersion(ctx context.Context, version Version) error {
+	if !c.versioned && c.loadChangesFunc == nil {
+		return errors.New("cache is not versioned")
+	}
+
+	var lastErr error
+	for c.Version() < version {
+		waiter, err := c.invalidateForWaiter()
+		if err != nil {
+			return err
+		}
+		c.invalidate(reasonManual)
+
+		err = c.wait(ctx, waiter)
+		if errors.Is(err, ErrClosed) {
+			return err
+		}
+		if err != nil && ctx.Err() == nil {
+			lastErr = err
+		}
+
+		if c.Version() >= version {
+			break
+		}
+
+		c.log.Trace().
+			Uint64("version", uint64(c.Version())).
+			Uint64("required_version", uint64(version)).
+			Msg("required version not loaded yet")
+
+		// do not reload immediately, data source may not be up to date yet
+		select {
+		case <-time.After(waitForVersionRetryDelay):
+		case <-ctx.Done():
+			if lastErr != nil {
+				return fmt.Errorf("%w (last reload error: %v)", ctx.Err(), lastErr)
+			}
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
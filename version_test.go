@@ -0,0 +1,119 @@
+package codebook
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moderntv/codebook-cache/internal/test_utils"
+)
+
+func TestVersion(t *testing.T) {
+	t.Run("testVersionWait", testVersionWait)
+	t.Run("testVersionWaitLagging", testVersionWaitLagging)
+	t.Run("testVersionWaitChanges", testVersionWaitChanges)
+	t.Run("testVersionNotVersioned", testVersionNotVersioned)
+}
+
+func testVersionWait(t *testing.T) {
+	t.Parallel()
+
+	source := newVersionedSource()
+	source.set("key1", 1)
+
+	c, err := New(Params[string, int]{
+		Context:              context.Background(),
+		Log:                  test_utils.Logger(),
+		Name:                 "testing_cache",
+		LoadAllVersionedFunc: source.loadAll,
+		Timeouts: Timeouts{
+			ReloadInterval: 10 * time.Second,
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, Version(1), c.Version())
+
+	// already loaded version does not trigger reload
+	assert.NoError(t, c.WaitForVersion(context.Background(), 1))
+	assert.Equal(t, int64(1), source.fullLoads.Load())
+
+	source.set("key1", 10)
+	assert.NoError(t, c.WaitForVersion(context.Background(), 2))
+	assert.Equal(t, Version(2), c.Version())
+	assert.Equal(t, test_utils.IntPointer(10), c.Get("key1"))
+}
+
+func testVersionWaitLagging(t *testing.T) {
+	t.Parallel()
+
+	// data source replica which is 3 loads behind
+	var loads atomic.Int64
+	c, err := New(Params[string, int]{
+		Context: context.Background(),
+		Log:     test_utils.Logger(),
+		Name:    "testing_cache",
+		LoadAllVersionedFunc: func(ctx context.Context) (map[string]*int, Version, error) {
+			return map[string]*int{}, Version(loads.Add(1)), nil
+		},
+		Timeouts: Timeouts{
+			ReloadInterval: 10 * time.Second,
+		},
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, c.WaitForVersion(context.Background(), 4))
+	assert.Equal(t, Version(4), c.Version())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, c.WaitForVersion(ctx, 100), context.DeadlineExceeded)
+}
+
+func testVersionWaitChanges(t *testing.T) {
+	t.Parallel()
+
+	source := newVersionedSource()
+	source.set("key1", 1)
+
+	// all items are not versioned, version is known from loaded changes
+	c, err := New(Params[string, int]{
+		Context: context.Background(),
+		Log:     test_utils.Logger(),
+		Name:    "testing_cache",
+		LoadAllFunc: func(ctx context.Context) (map[string]*int, error) {
+			entries, _, err := source.loadAll(ctx)
+			return entries, err
+		},
+		LoadChangesFunc: source.loadChanges,
+		Timeouts: Timeouts{
+			ReloadInterval: 5 * time.Second,
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, Version(0), c.Version())
+
+	source.set("key2", 2)
+	assert.NoError(t, c.WaitForVersion(context.Background(), 2))
+	assert.Equal(t, Version(2), c.Version())
+	assert.Equal(t, test_utils.IntPointer(2), c.Get("key2"))
+}
+
+func testVersionNotVersioned(t *testing.T) {
+	t.Parallel()
+
+	c, err := New(Params[string, int]{
+		Context: context.Background(),
+		Log:     test_utils.Logger(),
+		Name:    "testing_cache",
+		LoadAllFunc: func(ctx context.Context) (map[string]*int, error) {
+			return map[string]*int{}, nil
+		},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, Version(0), c.Version())
+	assert.Error(t, c.WaitForVersion(context.Background(), 1))
+}
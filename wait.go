@@ -0,0 +1,88 @@
+package codebook
+
+import (
+	"context"
+)
+
+// reloadWaiter waits for the first reload started after reload with sequence number `after`
+type reloadWaiter struct {
+	after  uint64
+	result chan error
+}
+
+// InvalidateAndWait immediately reloads all items (regardless of `Timeouts.ReloadDelay`) and waits
+// until a reload started after this call finishes. Returns the reload error (e.g. loading error)
+// or `ctx` error when `ctx` is done before.
+// When a reload is already running, one more reload is performed after it and this call waits for it.
+func (c *Cache[K, T]) InvalidateAndWait(ctx context.Context) error {
+	waiter, err := c.invalidateForWaiter()
+	if err != nil {
+		return err
+	}
+
+	go c.reload(reasonManual)
+
+	return c.wait(ctx, waiter)
+}
+
+// InvalidateAndWaitDelayed invalidates all items like `InvalidateAll` (respecting `Timeouts.ReloadDelay`,
+// so it can be aggregated with other invalidations) and waits until a reload started after this call
+// finishes. Returns the reload error or `ctx` error when `ctx` is done before.
+func (c *Cache[K, T]) InvalidateAndWaitDelayed(ctx context.Context) error {
+	waiter, err := c.invalidateForWaiter()
+	if err != nil {
+		return err
+	}
+
+	c.invalidate(reasonManual)
+
+	return c.wait(ctx, waiter)
+}
+
+// invalidateForWaiter marks all items as invalidated and registers waiter for the next reload
+func (c *Cache[K, T]) invalidateForWaiter() (waiter reloadWaiter, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		err = ErrClosed
+		return
+	}
+
+	c.pendingFull = true
+	waiter = reloadWaiter{
+		after:  c.reloadSeq,
+		result: make(chan error, 1),
+	}
+	c.waiters = append(c.waiters, waiter)
+
+	return
+}
+
+func (c *Cache[K, T]) wait(ctx context.Context, waiter reloadWaiter) error {
+	select {
+	case err := <-waiter.result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// releaseWaitersLocked sends `err` to all waiters waiting for reload with sequence number `seq`
+func (c *Cache[K, T]) releaseWaitersLocked(seq uint64, err error) {
+	waiting := c.waiters[:0]
+	for _, waiter := range c.waiters {
+		if waiter.after >= seq {
+			waiting = append(waiting, waiter)
+			continue
+		}
+
+		waiter.result <- err
+	}
+
+	// clear released waiters from the rest of underlying array
+	for i := len(waiting); i < len(c.waiters); i++ {
+		c.waiters[i] = reloadWaiter{}
+	}
+	c.waiters = waiting
+}
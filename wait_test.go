@@ -0,0 +1,145 @@
+package codebook
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moderntv/codebook-cache/internal/test_utils"
+)
+
+func TestWait(t *testing.T) {
+	t.Run("testWaitInvalidate", testWaitInvalidate)
+	t.Run("testWaitDuringReload", testWaitDuringReload)
+	t.Run("testWaitDelayed", testWaitDelayed)
+	t.Run("testWaitClosed", testWaitClosed)
+}
+
+// countingParams returns params of cache which loads its load count as value of key "count"
+func countingParams(loadCount *atomic.Int64, loadDelay *atomic.Int64, loadErr *atomic.Value) Params[string, int] {
+	return Params[string, int]{
+		Context: context.Background(),
+		Log:     test_utils.Logger(),
+		Name:    "testing_cache",
+		LoadAllFunc: func(ctx context.Context) (map[string]*int, error) {
+			time.Sleep(time.Duration(loadDelay.Load()))
+			if err, ok := loadErr.Load().(error); ok && err != nil {
+				return nil, err
+			}
+
+			return map[string]*int{
+				"count": test_utils.IntPointer(int(loadCount.Add(1))),
+			}, nil
+		},
+		Timeouts: Timeouts{
+			ReloadInterval: 10 * time.Second,
+		},
+	}
+}
+
+func testWaitInvalidate(t *testing.T) {
+	t.Parallel()
+
+	var loadCount, loadDelay atomic.Int64
+	var loadErr atomic.Value
+
+	c, err := New(countingParams(&loadCount, &loadDelay, &loadErr))
+	assert.NoError(t, err)
+
+	loadDelay.Store(int64(100 * time.Millisecond))
+	assert.NoError(t, c.InvalidateAndWait(context.Background()))
+	assert.Equal(t, test_utils.IntPointer(2), c.Get("count"))
+
+	// loader error is returned
+	loadErr.Store(errors.New("cannot load"))
+	err = c.InvalidateAndWait(context.Background())
+	assert.EqualError(t, err, "cannot load")
+	assert.Equal(t, test_utils.IntPointer(2), c.Get("count"))
+
+	// context limits waiting
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, c.InvalidateAndWait(ctx), context.DeadlineExceeded)
+}
+
+func testWaitDuringReload(t *testing.T) {
+	t.Parallel()
+
+	var loadCount, loadDelay atomic.Int64
+	var loadErr atomic.Value
+
+	c, err := New(countingParams(&loadCount, &loadDelay, &loadErr))
+	assert.NoError(t, err)
+
+	// reload started before the call is not enough
+	loadDelay.Store(int64(300 * time.Millisecond))
+	c.InvalidateAll()
+	time.Sleep(100 * time.Millisecond)
+
+	start := time.Now()
+	assert.NoError(t, c.InvalidateAndWait(context.Background()))
+	assert.Greater(t, time.Since(start), 400*time.Millisecond)
+	assert.Equal(t, test_utils.IntPointer(3), c.Get("count"))
+}
+
+func testWaitDelayed(t *testing.T) {
+	t.Parallel()
+
+	var loadCount, loadDelay atomic.Int64
+	var loadErr atomic.Value
+
+	params := countingParams(&loadCount, &loadDelay, &loadErr)
+	params.Timeouts.ReloadDelay = 500 * time.Millisecond
+	c, err := New(params)
+	assert.NoError(t, err)
+
+	// first invalidation is not delayed
+	c.InvalidateAll()
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, test_utils.IntPointer(2), c.Get("count"))
+
+	// waiting callers are released by one aggregated reload
+	start := time.Now()
+	results := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			results <- c.InvalidateAndWaitDelayed(context.Background())
+		}()
+	}
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, <-results)
+	}
+
+	assert.Greater(t, time.Since(start), 300*time.Millisecond)
+	assert.Equal(t, test_utils.IntPointer(3), c.Get("count"))
+}
+
+func testWaitClosed(t *testing.T) {
+	t.Parallel()
+
+	var loadCount, loadDelay atomic.Int64
+	var loadErr atomic.Value
+
+	params := countingParams(&loadCount, &loadDelay, &loadErr)
+	params.Timeouts.ReloadDelay = 5 * time.Second
+	c, err := New(params)
+	assert.NoError(t, err)
+
+	// the second invalidation is delayed
+	c.InvalidateAll()
+	time.Sleep(100 * time.Millisecond)
+
+	result := make(chan error, 1)
+	go func() {
+		result <- c.InvalidateAndWaitDelayed(context.Background())
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	assert.NoError(t, c.Close(context.Background()))
+	assert.ErrorIs(t, <-result, ErrClosed)
+	assert.ErrorIs(t, c.InvalidateAndWait(context.Background()), ErrClosed)
+}